@@ -0,0 +1,225 @@
+package goexpr
+
+import (
+	"fmt"
+)
+
+// KeyAccess describes a read or a write of a request context heap reference.
+type KeyAccess struct {
+	// The reference key
+	Key interface{}
+	// The name of the reference as specified in the rule definition
+	Name string
+	// The expected type of the value read or the type of the value written
+	Type TypeSignature
+	// The source position of the expression accessing the reference
+	Line int
+	Col  int
+}
+
+// Dependencies holds the result of a static dependency analysis of an expression.
+// Note that the analysis is static. All sub-expressions are considered (e.g. both the then- and else-Expression of an
+// if expression) even though only some of them may be evaluated at runtime.
+type Dependencies struct {
+	// All reads of heap references in evaluation order.
+	Reads []KeyAccess
+	// All writes of heap references in evaluation order (including for loop keys).
+	Writes []KeyAccess
+	// Reads of keys that are not written before the read but that are written by a later expression in the same
+	// sequence. Such a read will probably not read the value the rule author intended.
+	ReadsBeforeWrite []KeyAccess
+	// Reads of keys not written before the read
+	inputs []KeyAccess
+}
+
+// ReadSet returns the unique set of keys read by the expression. For each key the first read is returned.
+func (d Dependencies) ReadSet() []KeyAccess {
+	return uniqueKeyAccesses(d.Reads)
+}
+
+// WriteSet returns the unique set of keys written by the expression. For each key the first write is returned.
+func (d Dependencies) WriteSet() []KeyAccess {
+	return uniqueKeyAccesses(d.Writes)
+}
+
+// InputSet returns the unique set of keys read by the expression before they are written by the expression.
+// That is the keys that have to be provided by the request context (e.g. prefetched) before evaluation.
+func (d Dependencies) InputSet() []KeyAccess {
+	return uniqueKeyAccesses(d.inputs)
+}
+
+// AnalyzeDependencies does a static analysis of an expression and returns the heap references read and written
+// by the expression.
+func AnalyzeDependencies(expr Expression) Dependencies {
+	var da dependencyAnalyzer
+	da.analyze(expr)
+	return da.deps
+}
+
+type dependencyAnalyzer struct {
+	deps Dependencies
+	// Keys written so far in evaluation order
+	written keySet
+	// For each read, true if the key was written before the read
+	writtenBefore []bool
+	// Reads already reported as read before write
+	reported map[int]bool
+	// Names bound by the enclosing let expressions
+	scope letScope
+}
+
+func (da *dependencyAnalyzer) read(access KeyAccess) {
+	if da.scope.bound(access.Key) {
+		return
+	}
+	writtenBefore := da.written.contains(access.Key)
+	if !writtenBefore {
+		da.deps.inputs = append(da.deps.inputs, access)
+	}
+	da.writtenBefore = append(da.writtenBefore, writtenBefore)
+	da.deps.Reads = append(da.deps.Reads, access)
+}
+
+func (da *dependencyAnalyzer) write(access KeyAccess) {
+	if da.scope.bound(access.Key) {
+		return
+	}
+	da.written.add(access.Key)
+	da.deps.Writes = append(da.deps.Writes, access)
+}
+
+func (da *dependencyAnalyzer) analyze(expr Expression) {
+	switch op := expr.(type) {
+	case *exprAssign:
+		da.analyzeChildren(op)
+		if op.source == RSHeap {
+			da.write(KeyAccess{Key: op.key, Name: op.name, Type: op.valueOp.ResultType(), Line: op.Line(), Col: op.Col()})
+		}
+	case *exprFor:
+		// The loop key is assigned after the list and break expressions are evaluated but before the loop expression
+		da.analyze(op.opList)
+		if op.opBreak != nil {
+			da.analyze(op.opBreak)
+		}
+		da.write(KeyAccess{Key: op.key, Name: op.key, Type: op.ResultType(), Line: op.Line(), Col: op.Col()})
+		da.analyze(op.opLoop)
+	case *exprLambda:
+		// The keys are assigned after the list (and init) expressions are evaluated but before the body expression
+		da.analyze(op.opList)
+		if op.lt == LATReduce {
+			da.analyze(op.opInit)
+		}
+		unit := op.opList.ResultType().UnitType
+		da.write(KeyAccess{Key: op.key, Name: op.key, Type: *unit, Line: op.Line(), Col: op.Col()})
+		if op.lt == LATReduce {
+			da.write(KeyAccess{Key: op.accKey, Name: op.accKey, Type: op.ResultType(), Line: op.Line(), Col: op.Col()})
+		}
+		da.analyze(op.opBody)
+	case *exprSort:
+		// The key is assigned after the list expression is evaluated but before the key expression
+		da.analyze(op.opList)
+		if op.opKey != nil {
+			unit := op.opList.ResultType().UnitType
+			da.write(KeyAccess{Key: op.key, Name: op.key, Type: *unit, Line: op.Line(), Col: op.Col()})
+			da.analyze(op.opKey)
+		}
+	case *exprSwitch:
+		// The subject key is assigned after the subject expression is evaluated but before the arms
+		children := op.Children()
+		if op.key != "" {
+			da.analyze(children[0])
+			da.write(KeyAccess{Key: op.key, Name: op.key, Type: op.opSubject.ResultType(), Line: op.Line(),
+				Col: op.Col()})
+			children = children[1:]
+		}
+		for _, child := range children {
+			da.analyze(child)
+		}
+	case *exprLet:
+		// Bound names are not read from (or written to) the heap
+		if da.scope == nil {
+			da.scope = make(letScope)
+		}
+		for _, binding := range op.bindings {
+			da.analyze(binding.Value)
+			da.scope[binding.Name]++
+		}
+		da.analyze(op.opBody)
+		for _, binding := range op.bindings {
+			da.scope[binding.Name]--
+		}
+	case *exprTry:
+		da.analyze(op.opTry)
+		if op.errName == "" {
+			da.analyze(op.opCatch)
+			break
+		}
+		if da.scope == nil {
+			da.scope = make(letScope)
+		}
+		da.scope[op.errName]++
+		da.analyze(op.opCatch)
+		da.scope[op.errName]--
+	case *exprReference:
+		da.analyzeChildren(op)
+		if op.source == RSHeap {
+			da.read(KeyAccess{Key: op.key, Name: op.name, Type: op.ResultType(), Line: op.Line(), Col: op.Col()})
+		}
+	case *exprSequence:
+		da.analyzeSequence(op)
+	default:
+		da.analyzeChildren(expr)
+	}
+}
+
+func (da *dependencyAnalyzer) analyzeChildren(expr Expression) {
+	for _, child := range expr.Children() {
+		da.analyze(child)
+	}
+}
+
+func (da *dependencyAnalyzer) analyzeSequence(op *exprSequence) {
+	// Record the first read and write for each sub-expression in the sequence
+	readStart := make([]int, len(op.ops)+1)
+	writeStart := make([]int, len(op.ops)+1)
+	for i, child := range op.ops {
+		readStart[i], writeStart[i] = len(da.deps.Reads), len(da.deps.Writes)
+		da.analyze(child)
+	}
+	readStart[len(op.ops)], writeStart[len(op.ops)] = len(da.deps.Reads), len(da.deps.Writes)
+
+	// Check for reads of keys not written before the read but written by a later sub-expression
+	for i := range op.ops {
+		var laterWrites keySet
+		for _, write := range da.deps.Writes[writeStart[i+1]:] {
+			laterWrites.add(write.Key)
+		}
+		for ri := readStart[i]; ri < readStart[i+1]; ri++ {
+			if da.writtenBefore[ri] || da.reported[ri] || !laterWrites.contains(da.deps.Reads[ri].Key) {
+				continue
+			}
+			if da.reported == nil {
+				da.reported = make(map[int]bool)
+			}
+			da.reported[ri] = true
+			da.deps.ReadsBeforeWrite = append(da.deps.ReadsBeforeWrite, da.deps.Reads[ri])
+		}
+	}
+}
+
+func uniqueKeyAccesses(accesses []KeyAccess) []KeyAccess {
+	var keys keySet
+	var unique []KeyAccess
+	for _, access := range accesses {
+		if keys.contains(access.Key) {
+			continue
+		}
+		keys.add(access.Key)
+		unique = append(unique, access)
+	}
+	return unique
+}
+
+func (ka KeyAccess) String() string {
+	return fmt.Sprintf("%v %v (%d:%d)", ka.Key, ka.Type, ka.Line, ka.Col)
+}
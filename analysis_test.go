@@ -0,0 +1,86 @@
+package goexpr
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAnalyzeDependencies(t *testing.T) {
+	l, c := 1, 2
+	refCount := NewExprHeapReference("count", "count", 3, 4)
+	refCount.ExpectedResultType(NewScalarTypeSignature(VTInteger))
+	op := NewExprSequence([]Expression{
+		// Read of "b" before it is written later in the sequence
+		NewExprAssign("a", "a", NewExprHeapReference("b", "b", 1, 5), nil, RSHeap, 1, 1),
+		NewExprFor(
+			NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{
+				NewExprValueInteger(1),
+			}), l, c),
+			NewExprCompareMust(CTLess, NewExprHeapReference("k1", "k1", l, c), refCount, l, c),
+			nil,
+			"k1", 2, 1),
+		NewExprAssign("b", "b", NewExprHeapReference("a", "a", 5, 5), nil, RSHeap, 5, 1),
+	}, l, c)
+	deps := AnalyzeDependencies(op)
+
+	tests := []struct {
+		name     string
+		accesses []KeyAccess
+		expected string
+	}{
+		{"reads", deps.Reads,
+			"[b {string} (1:5) k1 {integer} (1:2) count {integer} (3:4) a {string} (5:5)]"},
+		{"writes", deps.Writes,
+			"[a {string} (1:1) k1 {integer} (2:1) b {string} (5:1)]"},
+		{"readsBeforeWrite", deps.ReadsBeforeWrite,
+			"[b {string} (1:5)]"},
+		{"readSet", deps.ReadSet(),
+			"[b {string} (1:5) k1 {integer} (1:2) count {integer} (3:4) a {string} (5:5)]"},
+		{"writeSet", deps.WriteSet(),
+			"[a {string} (1:1) k1 {integer} (2:1) b {string} (5:1)]"},
+		{"inputSet", deps.InputSet(),
+			"[b {string} (1:5) count {integer} (3:4)]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := fmt.Sprint(test.accesses)
+			if actual != test.expected {
+				t.Errorf("wrong key accesses.\nactual:   %s\nexpected: %s", actual, test.expected)
+			}
+		})
+	}
+}
+
+func TestAnalyzeDependencies_NoReadBeforeWrite(t *testing.T) {
+	l, c := 1, 2
+	op := NewExprSequence([]Expression{
+		NewExprAssign("a", "a", NewExprConstant(NewExprValueString("foo"), l, c), nil, RSHeap, l, c),
+		NewExprHeapReference("a", "a", l, c),
+		NewExprAssign("a", "a", NewExprConstant(NewExprValueString("bar"), l, c), nil, RSHeap, l, c),
+	}, l, c)
+	deps := AnalyzeDependencies(op)
+	if len(deps.ReadsBeforeWrite) != 0 {
+		t.Errorf("unexpected reads before write: %v", deps.ReadsBeforeWrite)
+	}
+	if len(deps.InputSet()) != 0 {
+		t.Errorf("unexpected input set: %v", deps.InputSet())
+	}
+}
+
+func TestAnalyzeDependencies_ReduceInit(t *testing.T) {
+	l, c := 1, 2
+	// The init expression is evaluated before the lambda keys are assigned. A read of the key in the init expression
+	// is therefore an input.
+	op := NewExprReduceMust(
+		NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+			NewExprValueString("foo"),
+		}), l, c),
+		NewExprHeapReference("x", "x", 3, 4),
+		NewExprHeapReference("acc", "acc", 5, 6),
+		"x", "acc", l, c)
+	deps := AnalyzeDependencies(op)
+	expected := "[x {string} (3:4)]"
+	if actual := fmt.Sprint(deps.InputSet()); actual != expected {
+		t.Errorf("wrong input set.\nactual:   %s\nexpected: %s", actual, expected)
+	}
+}
@@ -0,0 +1,261 @@
+package goexpr
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Coverage records which branches of the expressions in an expression tree are taken across evaluations.
+// An expression tree is covered by evaluating the instrumented expression returned by Instrument(). The same
+// coverage may be used for many (also concurrent) evaluations.
+// The following branches are recorded.
+// If: "then" (the check expression evaluated to true) and "else" (the check expression evaluated to false).
+// Logical and/or: "short-circuit" (the result was given by the left operand) and "right" (the right operand
+// was evaluated).
+// Search with a default expression: "default" (the default expression was evaluated) and "no default" (the search
+// was evaluated without evaluating the default expression).
+// For: "loop" (the loop expression was evaluated).
+// Lambda: "body" (the body expression was evaluated).
+// Coalesce (??) and default: "value" (the first operand was not nil) and "fallback" (a later operand was
+// evaluated).
+// Try: "no error" (the try expression was evaluated without an error) and "catch" (the catch expression was
+// evaluated).
+// Switch: one branch for each arm (the arm was selected) and "default" or "no match" (no arm was selected).
+type Coverage struct {
+	mu sync.Mutex
+	// Covered expressions in the order they were instrumented
+	exprs []Expression
+	known map[Expression]bool
+	// Number of times each expression was evaluated
+	counts map[Expression]int
+	// Number of times each expression was evaluated without an error
+	okCounts map[Expression]int
+	// Number of times each boolean expression evaluated to true and false
+	trueCounts  map[Expression]int
+	falseCounts map[Expression]int
+}
+
+// NewCoverage creates a new coverage collector.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		known:       make(map[Expression]bool),
+		counts:      make(map[Expression]int),
+		okCounts:    make(map[Expression]int),
+		trueCounts:  make(map[Expression]int),
+		falseCounts: make(map[Expression]int),
+	}
+}
+
+// Instrument returns an instrumented copy of the expression tree. Each time the instrumented expression is
+// evaluated the branches taken are recorded for the expressions in the original expression tree.
+func (c *Coverage) Instrument(expr Expression) (Expression, error) {
+	c.mu.Lock()
+	Inspect(expr, func(e Expression) bool {
+		if e != nil && !c.known[e] {
+			c.known[e] = true
+			c.exprs = append(c.exprs, e)
+		}
+		return true
+	})
+	c.mu.Unlock()
+	return instrument(expr, func(orig Expression, eval func() (Value, error)) (Value, error) {
+		value, err := eval()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.counts[orig]++
+		if err == nil {
+			c.okCounts[orig]++
+		}
+		if err == nil && value.Type.IsValueType(VTBoolean) && !value.Nil() {
+			if value.Value.(bool) {
+				c.trueCounts[orig]++
+			} else {
+				c.falseCounts[orig]++
+			}
+		}
+		return value, err
+	})
+}
+
+// Branch holds the coverage of a single branch of an expression.
+type Branch struct {
+	// The source position of the expression
+	Line int
+	Col  int
+	// Short description of the expression (e.g. "if")
+	Description string
+	// The branch of the expression (e.g. "then")
+	Branch string
+	// Number of times the branch was taken
+	Count int
+}
+
+// Covered returns true if the branch was taken at least once
+func (b Branch) Covered() bool {
+	return b.Count > 0
+}
+
+// Report returns a coverage report for all branches of the instrumented expressions sorted by source position.
+func (c *Coverage) Report() CoverageReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var branches []Branch
+	for _, expr := range c.exprs {
+		branch := func(name string, count int) {
+			branches = append(branches, Branch{
+				Line:        expr.Line(),
+				Col:         expr.Col(),
+				Description: exprDescription(expr),
+				Branch:      name,
+				Count:       count,
+			})
+		}
+		switch op := expr.(type) {
+		case *exprIf:
+			branch("then", c.trueCounts[op.checkOp])
+			branch("else", c.falseCounts[op.checkOp])
+		case *exprLogical:
+			switch op.lt {
+			case LTAnd:
+				branch("short-circuit", c.falseCounts[op.opLeft])
+				branch("right", c.counts[op.opRight])
+			case LTOr:
+				branch("short-circuit", c.trueCounts[op.opLeft])
+				branch("right", c.counts[op.opRight])
+			}
+		case *exprSearch:
+			if op.opDef != nil {
+				branch("default", c.counts[op.opDef])
+				branch("no default", c.counts[op]-c.counts[op.opDef])
+			}
+		case *exprFor:
+			branch("loop", c.counts[op.opLoop])
+		case *exprLambda:
+			branch("body", c.counts[op.opBody])
+		case *exprNil:
+			if op.no != NOIsNil {
+				branch("value", c.counts[op]-c.counts[op.ops[1]])
+				branch("fallback", c.counts[op.ops[1]])
+			}
+		case *exprTry:
+			branch("no error", c.okCounts[op.opTry])
+			branch("catch", c.counts[op.opCatch])
+		case *exprSwitch:
+			selected := 0
+			for _, arm := range op.arms {
+				branch(armTestString(arm), c.counts[arm.Result])
+				selected += c.counts[arm.Result]
+			}
+			if op.opDefault != nil {
+				branch("default", c.counts[op]-selected)
+			} else {
+				branch("no match", c.counts[op]-selected)
+			}
+		}
+	}
+	sort.SliceStable(branches, func(i, j int) bool {
+		if branches[i].Line != branches[j].Line {
+			return branches[i].Line < branches[j].Line
+		}
+		return branches[i].Col < branches[j].Col
+	})
+	return CoverageReport{Branches: branches}
+}
+
+// CoverageReport holds the branch coverage of an expression tree.
+type CoverageReport struct {
+	Branches []Branch
+}
+
+// Covered returns the number of covered branches
+func (cr CoverageReport) Covered() int {
+	covered := 0
+	for _, branch := range cr.Branches {
+		if branch.Covered() {
+			covered++
+		}
+	}
+	return covered
+}
+
+// Percent returns the percentage of covered branches. If there are no branches 100 is returned.
+func (cr CoverageReport) Percent() float64 {
+	if len(cr.Branches) == 0 {
+		return 100
+	}
+	return 100 * float64(cr.Covered()) / float64(len(cr.Branches))
+}
+
+// Summary returns a one line coverage summary similar to "go test -cover".
+func (cr CoverageReport) Summary() string {
+	return fmt.Sprintf("coverage: %.1f%% of branches (%d/%d)", cr.Percent(), cr.Covered(), len(cr.Branches))
+}
+
+// Text returns a text report with the summary followed by one line per branch (position, expression, branch
+// and count).
+func (cr CoverageReport) Text() string {
+	var sb strings.Builder
+	sb.WriteString(cr.Summary())
+	sb.WriteString("\n")
+	for _, branch := range cr.Branches {
+		sb.WriteString(fmt.Sprintf("%d:%d\t%s\t%s\t%d", branch.Line, branch.Col, branch.Description, branch.Branch,
+			branch.Count))
+		if !branch.Covered() {
+			sb.WriteString("\tnot covered")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// HTML returns an HTML report where the rule source is highlighted. Lines with branches where all branches
+// are covered are green, lines where no branches are covered are red and lines with partial coverage are yellow.
+// The branches of a line are listed in the title (tooltip) of the line.
+func (cr CoverageReport) HTML(source string) string {
+	lineBranches := make(map[int][]Branch)
+	for _, branch := range cr.Branches {
+		lineBranches[branch.Line] = append(lineBranches[branch.Line], branch)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Rule coverage</title>\n")
+	sb.WriteString("<style>\n")
+	sb.WriteString("body { font-family: monospace; }\n")
+	sb.WriteString(".cov { background-color: #c8f0c8; }\n")
+	sb.WriteString(".partial { background-color: #f0f0a0; }\n")
+	sb.WriteString(".uncov { background-color: #f0c8c8; }\n")
+	sb.WriteString("</style>\n</head>\n<body>\n")
+	sb.WriteString(fmt.Sprintf("<p>%s</p>\n<pre>\n", html.EscapeString(cr.Summary())))
+	for i, line := range strings.Split(source, "\n") {
+		lineNo := i + 1
+		branches := lineBranches[lineNo]
+		text := html.EscapeString(line)
+		if len(branches) == 0 {
+			sb.WriteString(fmt.Sprintf("<span>%s</span>\n", text))
+			continue
+		}
+		covered := 0
+		titles := make([]string, 0, len(branches))
+		for _, branch := range branches {
+			if branch.Covered() {
+				covered++
+			}
+			titles = append(titles, fmt.Sprintf("%d:%d %s %s: %d", branch.Line, branch.Col, branch.Description,
+				branch.Branch, branch.Count))
+		}
+		class := "partial"
+		switch covered {
+		case len(branches):
+			class = "cov"
+		case 0:
+			class = "uncov"
+		}
+		sb.WriteString(fmt.Sprintf("<span class=\"%s\" title=\"%s\">%s</span>\n", class,
+			html.EscapeString(strings.Join(titles, "\n")), text))
+	}
+	sb.WriteString("</pre>\n</body>\n</html>\n")
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package goexpr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	source := `(if (state == "ok")
+  then (ok or fallback)
+  else false)`
+	refOk := NewExprHeapReference("ok", "ok", 2, 9)
+	refOk.ExpectedResultType(NewScalarTypeSignature(VTBoolean))
+	refFallback := NewExprHeapReference("fallback", "fallback", 2, 15)
+	refFallback.ExpectedResultType(NewScalarTypeSignature(VTBoolean))
+	op := NewExprIf(
+		NewExprCompareMust(CTEqual, NewExprHeapReference("state", "state", 1, 6),
+			NewExprConstant(NewExprValueString("ok"), 1, 15), 1, 5),
+		NewExprLogical(LTOr, refOk, refFallback, 2, 8),
+		NewExprConstant(EvBooleanFalse, 3, 8), 1, 1)
+
+	cov := NewCoverage()
+	inst, err := cov.Instrument(op)
+	if err != nil {
+		t.Errorf("unexpected instrument error: %v", err)
+		return
+	}
+	for _, values := range []map[string]string{
+		{"state": "ok", "ok": "true"},
+		{"state": "ok", "ok": "true", "fallback": "false"},
+	} {
+		_, err := inst.Evaluate(newTestRequestContext(values))
+		if err != nil {
+			t.Errorf("unexpected evaluation error: %v", err)
+			return
+		}
+	}
+
+	report := cov.Report()
+	expected := `coverage: 50.0% of branches (2/4)
+1:1	if	then	2
+1:1	if	else	0	not covered
+2:8	or	short-circuit	2
+2:8	or	right	0	not covered
+`
+	if report.Text() != expected {
+		t.Errorf("wrong text report.\nactual:\n%s\nexpected:\n%s", report.Text(), expected)
+	}
+
+	htmlReport := report.HTML(source)
+	for _, s := range []string{
+		`<p>coverage: 50.0% of branches (2/4)</p>`,
+		`<span class="partial" title="1:1 if then: 2` + "\n" + `1:1 if else: 0">(if (state == &#34;ok&#34;)</span>`,
+		`<span class="partial" title="2:8 or short-circuit: 2` + "\n" + `2:8 or right: 0">  then (ok or fallback)</span>`,
+		`<span>  else false)</span>`,
+	} {
+		if !strings.Contains(htmlReport, s) {
+			t.Errorf("HTML report doesn't contain %s\nreport:\n%s", s, htmlReport)
+		}
+	}
+}
+
+func TestCoverageReport_Percent(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  CoverageReport
+		percent float64
+	}{
+		{"empty", CoverageReport{}, 100},
+		{"none", CoverageReport{Branches: []Branch{{Count: 0}, {Count: 0}}}, 0},
+		{"partial", CoverageReport{Branches: []Branch{{Count: 3}, {Count: 0}, {Count: 1}, {Count: 0}}}, 50},
+		{"all", CoverageReport{Branches: []Branch{{Count: 3}, {Count: 1}}}, 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.report.Percent() != test.percent {
+				t.Errorf("wrong percent (%v != %v)", test.report.Percent(), test.percent)
+			}
+		})
+	}
+}
+
+func TestCoverage_TryUncaught(t *testing.T) {
+	l, c := 1, 2
+	// Only reference errors are caught. A conversion error propagates and is not counted as "no error".
+	op := NewExprTryMust(
+		NewExprCastMust(CFToInt, NewExprHeapReference("n", "n", l, c), l, c),
+		NewExprConstant(NewExprValueInteger(-1), l, c),
+		[]ErrorKind{EKReference}, "", l, c)
+	cov := NewCoverage()
+	inst, err := cov.Instrument(op)
+	if err != nil {
+		t.Errorf("unexpected instrument error: %v", err)
+		return
+	}
+	if _, err := inst.Evaluate(newTestRequestContext(map[string]string{"n": "5"})); err != nil {
+		t.Errorf("unexpected evaluation error: %v", err)
+	}
+	if _, err := inst.Evaluate(newTestRequestContext(map[string]string{"n": "five"})); err == nil {
+		t.Errorf("expected evaluation error")
+	}
+	expected := `coverage: 50.0% of branches (1/2)
+1:2	try	no error	1
+1:2	try	catch	0	not covered
+`
+	if text := cov.Report().Text(); text != expected {
+		t.Errorf("wrong text report.\nactual:\n%s\nexpected:\n%s", text, expected)
+	}
+}
@@ -0,0 +1,251 @@
+package goexpr
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Rounding mode used when a decimal is rounded to fewer decimal places
+type RoundingMode string
+
+const (
+	// Round towards positive infinity
+	RMCeiling RoundingMode = "ceiling"
+	// Round towards zero (truncate)
+	RMDown RoundingMode = "down"
+	// Round towards negative infinity
+	RMFloor RoundingMode = "floor"
+	// Round to the nearest neighbour. If both neighbours are equally near round towards zero.
+	RMHalfDown RoundingMode = "halfDown"
+	// Round to the nearest neighbour. If both neighbours are equally near round to the even neighbour (bankers
+	// rounding).
+	RMHalfEven RoundingMode = "halfEven"
+	// Round to the nearest neighbour. If both neighbours are equally near round away from zero.
+	RMHalfUp RoundingMode = "halfUp"
+	// Round away from zero
+	RMUp RoundingMode = "up"
+)
+
+// ValidRoundingMode returns true if the rounding mode is a known rounding mode
+func ValidRoundingMode(rm RoundingMode) bool {
+	switch rm {
+	case RMCeiling, RMDown, RMFloor, RMHalfDown, RMHalfEven, RMHalfUp, RMUp:
+		return true
+	}
+	return false
+}
+
+// Decimal is an arbitrary precision fixed-point decimal number. The value of a decimal is unscaled * 10^-scale
+// (e.g. 12.50 has the unscaled value 1250 and the scale 2). Addition, subtraction and multiplication are exact.
+// Division and rounding to fewer decimal places use a rounding mode.
+// A decimal is immutable. The zero value of Decimal is 0 (with scale 0).
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// NewDecimal creates a decimal with the value unscaled * 10^-scale. The scale must not be negative.
+func NewDecimal(unscaled int64, scale int) Decimal {
+	if scale < 0 {
+		panic(fmt.Sprintf("negative decimal scale %d", scale))
+	}
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// NewDecimalFromInt creates a decimal (with scale 0) from an integer
+func NewDecimalFromInt(i int) Decimal {
+	return NewDecimal(int64(i), 0)
+}
+
+// ParseDecimal parses a decimal from its string representation (an optional sign followed by digits and an
+// optional fraction, e.g. "-12.50"). The scale of the decimal is the number of digits in the fraction.
+func ParseDecimal(s string) (Decimal, error) {
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	integer, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		integer, fraction = digits[:i], digits[i+1:]
+		if fraction == "" {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+	}
+	if integer == "" || strings.Trim(integer+fraction, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	unscaled, _ := new(big.Int).SetString(integer+fraction, 10)
+	if strings.HasPrefix(s, "-") {
+		unscaled.Neg(unscaled)
+	}
+	return Decimal{unscaled: unscaled, scale: len(fraction)}, nil
+}
+
+// ParseDecimalMust parses a decimal in the same way as ParseDecimal(). If the string is not a valid decimal a
+// panic is raised.
+func ParseDecimalMust(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// int returns the unscaled value of the decimal
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// rescale returns the unscaled value of the decimal for a scale not less than the scale of the decimal
+func (d Decimal) rescale(scale int) *big.Int {
+	return new(big.Int).Mul(d.int(), pow10(scale-d.scale))
+}
+
+// pow10 returns 10^n
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// Scale returns the number of decimal places of the decimal
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign returns -1, 0 or 1 if the decimal is negative, zero or positive respectively
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+// Add returns d + d2. The scale of the result is the largest scale of d and d2.
+func (d Decimal) Add(d2 Decimal) Decimal {
+	scale := maxInt(d.scale, d2.scale)
+	return Decimal{unscaled: new(big.Int).Add(d.rescale(scale), d2.rescale(scale)), scale: scale}
+}
+
+// Sub returns d - d2. The scale of the result is the largest scale of d and d2.
+func (d Decimal) Sub(d2 Decimal) Decimal {
+	scale := maxInt(d.scale, d2.scale)
+	return Decimal{unscaled: new(big.Int).Sub(d.rescale(scale), d2.rescale(scale)), scale: scale}
+}
+
+// Mul returns d * d2. The scale of the result is the sum of the scales of d and d2.
+func (d Decimal) Mul(d2 Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), d2.int()), scale: d.scale + d2.scale}
+}
+
+// Quo returns d / d2 with the specified scale rounded using the rounding mode. An error is returned if d2 is zero
+// or if the scale is negative.
+func (d Decimal) Quo(d2 Decimal, scale int, rm RoundingMode) (Decimal, error) {
+	if scale < 0 {
+		return Decimal{}, fmt.Errorf("negative decimal scale %d", scale)
+	}
+	if d2.Sign() == 0 {
+		return Decimal{}, fmt.Errorf("division by zero")
+	}
+	// d / d2 = (d.unscaled * 10^(scale + d2.scale - d.scale) / d2.unscaled) * 10^-scale
+	num, den := new(big.Int).Set(d.int()), new(big.Int).Set(d2.int())
+	if exp := scale + d2.scale - d.scale; exp >= 0 {
+		num.Mul(num, pow10(exp))
+	} else {
+		den.Mul(den, pow10(-exp))
+	}
+	return Decimal{unscaled: roundQuo(num, den, rm), scale: scale}, nil
+}
+
+// Round returns the decimal with the specified scale. If the scale is less than the scale of the decimal the
+// decimal is rounded using the rounding mode. The scale must not be negative.
+func (d Decimal) Round(scale int, rm RoundingMode) Decimal {
+	if scale < 0 {
+		panic(fmt.Sprintf("negative decimal scale %d", scale))
+	}
+	if scale >= d.scale {
+		return Decimal{unscaled: d.rescale(scale), scale: scale}
+	}
+	return Decimal{unscaled: roundQuo(d.int(), pow10(d.scale-scale), rm), scale: scale}
+}
+
+// roundQuo returns num / den rounded to an integer using the rounding mode
+func roundQuo(num, den *big.Int, rm RoundingMode) *big.Int {
+	sign := num.Sign() * den.Sign()
+	q, r := new(big.Int).QuoRem(new(big.Int).Abs(num), new(big.Int).Abs(den), new(big.Int))
+	if r.Sign() != 0 {
+		// Compare the remainder with half the divisor (2r <=> den)
+		half := new(big.Int).Lsh(r, 1).CmpAbs(den)
+		var away bool
+		switch rm {
+		case RMCeiling:
+			away = sign > 0
+		case RMDown:
+			away = false
+		case RMFloor:
+			away = sign < 0
+		case RMHalfDown:
+			away = half > 0
+		case RMHalfEven:
+			away = half > 0 || (half == 0 && q.Bit(0) == 1)
+		case RMHalfUp:
+			away = half >= 0
+		case RMUp:
+			away = true
+		default:
+			panic(fmt.Sprintf("unknown rounding mode %v", rm))
+		}
+		if away {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if sign < 0 {
+		q.Neg(q)
+	}
+	return q
+}
+
+// Cmp returns -1, 0 or 1 if d is less than, equal to or greater than d2 respectively. The scale doesn't affect
+// the comparison (e.g. 1.5 is equal to 1.50).
+func (d Decimal) Cmp(d2 Decimal) int {
+	scale := maxInt(d.scale, d2.scale)
+	return d.rescale(scale).Cmp(d2.rescale(scale))
+}
+
+// String returns the decimal with all its decimal places (e.g. "-12.50")
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if d.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON encodes the decimal as a JSON string (e.g. "12.50") to avoid the loss of precision of JSON numbers
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a decimal from a JSON string (see ParseDecimal())
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	*d, err = ParseDecimal(s)
+	return err
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
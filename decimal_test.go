@@ -0,0 +1,200 @@
+package goexpr
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		name  string
+		str   string
+		err   bool
+		scale int
+		res   string
+	}{
+		{"integer", "42", false, 0, "42"},
+		{"fraction", "12.50", false, 2, "12.50"},
+		{"negative", "-0.05", false, 2, "-0.05"},
+		{"plus", "+1.5", false, 1, "1.5"},
+		{"large", "123456789012345678901234567890.123", false, 3, "123456789012345678901234567890.123"},
+		{"empty", "", true, 0, ""},
+		{"noDigits", "-", true, 0, ""},
+		{"noInteger", ".5", true, 0, ""},
+		{"noFraction", "5.", true, 0, ""},
+		{"doubleSign", "--5", true, 0, ""},
+		{"exponent", "1e3", true, 0, ""},
+		{"comma", "1,5", true, 0, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := ParseDecimal(test.str)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if d.Scale() != test.scale || d.String() != test.res {
+				t.Errorf("wrong decimal.\nactual:   %v (scale %d)\nexpected: %v (scale %d)", d, d.Scale(), test.res,
+					test.scale)
+			}
+		})
+	}
+}
+
+func TestDecimal_Arithmetic(t *testing.T) {
+	d := ParseDecimalMust
+	tests := []struct {
+		name string
+		res  Decimal
+		str  string
+	}{
+		{"add", d("0.1").Add(d("0.2")), "0.3"},
+		{"addScale", d("1.5").Add(d("2.25")), "3.75"},
+		{"sub", d("1").Sub(d("0.01")), "0.99"},
+		{"subNegative", d("0.01").Sub(d("1.00")), "-0.99"},
+		{"mul", d("19.99").Mul(d("3")), "59.97"},
+		{"mulScale", d("0.5").Mul(d("-0.25")), "-0.125"},
+		{"zero", Decimal{}.Add(d("1.0")), "1.0"},
+		{"fromInt", NewDecimalFromInt(-7).Add(NewDecimal(5, 1)), "-6.5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.res.String() != test.str {
+				t.Errorf("wrong result.\nactual:   %v\nexpected: %v", test.res, test.str)
+			}
+		})
+	}
+}
+
+func TestDecimal_Round(t *testing.T) {
+	tests := []struct {
+		value string
+		rm    RoundingMode
+		res   string
+	}{
+		{"2.345", RMHalfUp, "2.35"},
+		{"2.345", RMHalfDown, "2.34"},
+		{"2.345", RMHalfEven, "2.34"},
+		{"2.355", RMHalfEven, "2.36"},
+		{"2.3451", RMHalfDown, "2.35"},
+		{"2.341", RMUp, "2.35"},
+		{"2.349", RMDown, "2.34"},
+		{"2.341", RMCeiling, "2.35"},
+		{"2.349", RMFloor, "2.34"},
+		{"-2.345", RMHalfUp, "-2.35"},
+		{"-2.345", RMHalfDown, "-2.34"},
+		{"-2.341", RMUp, "-2.35"},
+		{"-2.349", RMDown, "-2.34"},
+		{"-2.341", RMCeiling, "-2.34"},
+		{"-2.341", RMFloor, "-2.35"},
+		{"2.3", RMHalfUp, "2.30"},
+		{"-0.001", RMHalfUp, "0.00"},
+	}
+	for _, test := range tests {
+		t.Run(test.value+"/"+string(test.rm), func(t *testing.T) {
+			res := ParseDecimalMust(test.value).Round(2, test.rm)
+			if res.String() != test.res {
+				t.Errorf("wrong result.\nactual:   %v\nexpected: %v", res, test.res)
+			}
+		})
+	}
+}
+
+func TestDecimal_Quo(t *testing.T) {
+	d := ParseDecimalMust
+	tests := []struct {
+		name  string
+		d1    Decimal
+		d2    Decimal
+		scale int
+		rm    RoundingMode
+		res   string
+	}{
+		{"exact", d("10"), d("4"), 2, RMHalfUp, "2.50"},
+		{"third", d("100"), d("3"), 2, RMHalfEven, "33.33"},
+		{"twoThirds", d("2"), d("3"), 4, RMHalfUp, "0.6667"},
+		{"twoThirdsDown", d("2"), d("3"), 4, RMDown, "0.6666"},
+		{"negative", d("-1"), d("8"), 2, RMHalfEven, "-0.12"},
+		{"negativeDivisor", d("1"), d("-8"), 2, RMHalfUp, "-0.13"},
+		{"scaledDivisor", d("1.00"), d("0.003"), 0, RMFloor, "333"},
+		{"lessScale", d("12.3456"), d("1"), 1, RMHalfUp, "12.3"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.d1.Quo(test.d2, test.scale, test.rm)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if res.String() != test.res {
+				t.Errorf("wrong result.\nactual:   %v\nexpected: %v", res, test.res)
+			}
+		})
+	}
+	if _, err := d("1").Quo(d("0.00"), 2, RMHalfUp); err == nil {
+		t.Errorf("expected division by zero error")
+	}
+	if _, err := d("1234.5").Quo(d("1"), -2, RMHalfUp); err == nil {
+		t.Errorf("expected negative scale error")
+	}
+}
+
+func TestDecimal_RoundNegativeScale(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for negative scale")
+		}
+	}()
+	ParseDecimalMust("1234.5").Round(-2, RMHalfUp)
+}
+
+func TestDecimal_Cmp(t *testing.T) {
+	d := ParseDecimalMust
+	tests := []struct {
+		d1  Decimal
+		d2  Decimal
+		cmp int
+	}{
+		{d("1.5"), d("1.50"), 0},
+		{d("1.49"), d("1.5"), -1},
+		{d("-1"), d("-1.01"), 1},
+		{Decimal{}, d("0.000"), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.d1.String()+"/"+test.d2.String(), func(t *testing.T) {
+			if cmp := test.d1.Cmp(test.d2); cmp != test.cmp {
+				t.Errorf("wrong result.\nactual:   %v\nexpected: %v", cmp, test.cmp)
+			}
+		})
+	}
+}
+
+func TestDecimal_Json(t *testing.T) {
+	d := ParseDecimalMust("-1234567890.000000001")
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Errorf("unexpected marshal error: %v", err)
+		return
+	}
+	if string(data) != `"-1234567890.000000001"` {
+		t.Errorf("wrong json.\nactual:   %s\nexpected: %s", data, `"-1234567890.000000001"`)
+	}
+	var d2 Decimal
+	err = json.Unmarshal(data, &d2)
+	if err != nil {
+		t.Errorf("unexpected unmarshal error: %v", err)
+		return
+	}
+	if d2.String() != d.String() {
+		t.Errorf("wrong decimal.\nactual:   %v\nexpected: %v", d2, d)
+	}
+	if err := json.Unmarshal([]byte(`1.5`), &d2); err == nil {
+		t.Errorf("expected error unmarshalling a JSON number")
+	}
+}
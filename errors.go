@@ -0,0 +1,82 @@
+package goexpr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Evaluation error kind. The error kind is used to select the errors caught by a try Expression.
+type ErrorKind string
+
+const (
+	// The evaluation exceeded a budget (e.g. a time or cost limit enforced by the request context)
+	EKBudget ErrorKind = "budget"
+	// A value could not be converted to the expected type
+	EKConversion ErrorKind = "conversion"
+	// Any other error
+	EKOther ErrorKind = "other"
+	// A reference could not be read from the request context
+	EKReference ErrorKind = "reference"
+)
+
+// ErrBudgetExceeded may be returned (or wrapped) by a request context or a user-defined function to signal that
+// the evaluation exceeded a budget. The error is classified as EKBudget.
+var ErrBudgetExceeded = errors.New("evaluation budget exceeded")
+
+// ConversionError is returned when a value can't be converted to the expected type (e.g. by
+// NewExprValueFromString()). The error is classified as EKConversion.
+type ConversionError struct {
+	Err error
+}
+
+func (e *ConversionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// EvaluationError is an error returned when evaluating an Expression. It holds the error kind and the source
+// position of the Expression where the error occurred.
+type EvaluationError struct {
+	Kind ErrorKind
+	Line int
+	Col  int
+	Err  error
+}
+
+func (e *EvaluationError) Error() string {
+	return fmt.Sprintf("%s error (%d:%d): %v", e.Kind, e.Line, e.Col, e.Err)
+}
+
+func (e *EvaluationError) Unwrap() error {
+	return e.Err
+}
+
+// NewEvaluationError creates an evaluation error. If the error is already classified (see ErrorKindOf()) the
+// existing error kind is used instead of the specified error kind.
+func NewEvaluationError(kind ErrorKind, err error, line, col int) error {
+	if existing := ErrorKindOf(err); existing != EKOther {
+		kind = existing
+	}
+	return &EvaluationError{Kind: kind, Line: line, Col: col, Err: err}
+}
+
+// ErrorKindOf returns the kind of an error. An error wrapping an EvaluationError has the kind of the evaluation
+// error. An error wrapping ErrBudgetExceeded or a ConversionError has kind EKBudget and EKConversion respectively.
+// All other errors have kind EKOther.
+func ErrorKindOf(err error) ErrorKind {
+	var evalErr *EvaluationError
+	if errors.As(err, &evalErr) {
+		return evalErr.Kind
+	}
+	if errors.Is(err, ErrBudgetExceeded) {
+		return EKBudget
+	}
+	var convErr *ConversionError
+	if errors.As(err, &convErr) {
+		return EKConversion
+	}
+	return EKOther
+}
@@ -0,0 +1,46 @@
+package goexpr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	conversion := &ConversionError{Err: errors.New("not an integer")}
+	tests := []struct {
+		name string
+		err  error
+		kind ErrorKind
+	}{
+		{"other", errors.New("failed"), EKOther},
+		{"budget", ErrBudgetExceeded, EKBudget},
+		{"budgetWrapped", fmt.Errorf("failed: %w", ErrBudgetExceeded), EKBudget},
+		{"conversion", conversion, EKConversion},
+		{"evaluation", NewEvaluationError(EKReference, errors.New("failed"), 1, 2), EKReference},
+		{"evaluationKeepKind", NewEvaluationError(EKReference, fmt.Errorf("failed: %w", conversion), 1, 2),
+			EKConversion},
+		{"evaluationWrapped", fmt.Errorf("failed: %w", NewEvaluationError(EKBudget, errors.New("x"), 1, 2)),
+			EKBudget},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kind := ErrorKindOf(test.err)
+			if kind != test.kind {
+				t.Errorf("wrong error kind.\nactual:   %v\nexpected: %v", kind, test.kind)
+			}
+		})
+	}
+}
+
+func TestEvaluationError(t *testing.T) {
+	inner := errors.New("key not found")
+	err := NewEvaluationError(EKReference, inner, 3, 4)
+	expected := "reference error (3:4): key not found"
+	if err.Error() != expected {
+		t.Errorf("wrong error message.\nactual:   %v\nexpected: %v", err, expected)
+	}
+	if !errors.Is(err, inner) {
+		t.Errorf("evaluation error doesn't wrap the error")
+	}
+}
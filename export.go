@@ -0,0 +1,171 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Maximum number of characters of a traced value included in an exported node label
+const exportMaxValueLen = 40
+
+// ExportOptions holds the options used when exporting an expression tree.
+type ExportOptions struct {
+	// If specified each exported expression is annotated with the last value recorded for the expression in the
+	// trace (and the number of times the expression was evaluated if more than once).
+	Trace *EvaluationTrace
+}
+
+// ExportDOT returns a Graphviz DOT representation of the expression tree. Each expression is a node labeled with
+// the kind of expression, its result type and (optionally) its traced value. Sub-expressions are ordered in
+// evaluation order.
+func ExportDOT(expr Expression, opts ExportOptions) string {
+	var sb strings.Builder
+	sb.WriteString("digraph expression {\n")
+	sb.WriteString("  ordering=out;\n")
+	sb.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	exportTree(expr, func(id int, e Expression) {
+		label := strings.Join(exportLabel(e, opts), "\n")
+		sb.WriteString(fmt.Sprintf("  n%d [label=\"%s\"];\n", id, dotEscape(label)))
+	}, func(parent, child int) {
+		sb.WriteString(fmt.Sprintf("  n%d -> n%d;\n", parent, child))
+	})
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// ExportMermaid returns a Mermaid flowchart representation of the expression tree. The nodes are the same as for
+// ExportDOT().
+func ExportMermaid(expr Expression, opts ExportOptions) string {
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+	exportTree(expr, func(id int, e Expression) {
+		lines := exportLabel(e, opts)
+		for i, line := range lines {
+			lines[i] = mermaidEscape(line)
+		}
+		sb.WriteString(fmt.Sprintf("  n%d[\"%s\"]\n", id, strings.Join(lines, "<br/>")))
+	}, func(parent, child int) {
+		sb.WriteString(fmt.Sprintf("  n%d --> n%d\n", parent, child))
+	})
+	return sb.String()
+}
+
+// exportTree traverses the expression tree in pre-order and calls node for each expression (with a unique node id)
+// and edge for each parent/child relation.
+func exportTree(expr Expression, node func(id int, e Expression), edge func(parent, child int)) {
+	nextID := 0
+	var export func(e Expression) int
+	export = func(e Expression) int {
+		id := nextID
+		nextID++
+		node(id, e)
+		for _, child := range e.Children() {
+			edge(id, export(child))
+		}
+		return id
+	}
+	export(expr)
+}
+
+// exportLabel returns the label lines for an exported expression
+func exportLabel(expr Expression, opts ExportOptions) []string {
+	lines := []string{exprDescription(expr), expr.ResultType().String()}
+	if opts.Trace != nil {
+		values := opts.Trace.Values(expr)
+		if len(values) > 0 {
+			value := values[len(values)-1].String()
+			if utf8.RuneCountInString(value) > exportMaxValueLen {
+				value = string([]rune(value)[:exportMaxValueLen]) + "..."
+			}
+			if len(values) > 1 {
+				value = fmt.Sprintf("%s (x%d)", value, len(values))
+			}
+			lines = append(lines, "= "+value)
+		}
+	}
+	return lines
+}
+
+// exprDescription returns a short description of an expression not including its sub-expressions
+// (e.g. "if" or "reference my/ref").
+func exprDescription(expr Expression) string {
+	switch op := expr.(type) {
+	case *exprAggregate:
+		return string(op.af)
+	case *exprAssign:
+		return fmt.Sprintf("assign %v", op.key)
+	case *exprCall:
+		return "call " + op.f.Name
+	case *exprCast:
+		return string(op.cf)
+	case *exprCompare:
+		return "compare " + CompareTypeToString(op.ct)
+	case *exprConstant:
+		return "constant " + op.c.String()
+	case *exprDecimal:
+		return op.name()
+	case *exprFor:
+		return "foreach " + op.key
+	case *exprInstrumented:
+		return exprDescription(op.orig)
+	case *exprLambda:
+		return fmt.Sprintf("%s %s", op.lt, op.key)
+	case *exprLet:
+		return "let " + strings.Join(op.names(), ", ")
+	case *exprLogical:
+		return op.operator()
+	case *exprMapFunction:
+		return op.name()
+	case *exprNil:
+		return string(op.no)
+	case *exprReference:
+		if op.indexed() {
+			return fmt.Sprintf("reference [%v]", op.key)
+		}
+		if op.source == RSValue {
+			return fmt.Sprintf("reference .%v", op.key)
+		}
+		return fmt.Sprintf("reference %v", op.key)
+	case *exprRegexp:
+		return string(op.rf)
+	case *exprSearch:
+		if op.searchType == STFindAll {
+			return "find all"
+		}
+		return string(op.searchType)
+	case *exprSet:
+		return string(op.so)
+	case *exprSort:
+		if op.opKey != nil {
+			return "sort " + op.key
+		}
+		return "sort"
+	case *exprString:
+		return string(op.sf)
+	case *exprTypeTest:
+		return strings.TrimSpace(op.suffix())
+	}
+	// Use the expression type name (e.g. exprSequence => sequence)
+	name := strings.TrimPrefix(fmt.Sprintf("%T", expr), "*goexpr.")
+	name = strings.TrimPrefix(name, "expr")
+	if name == "" {
+		return name
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+func dotEscape(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return strings.Replace(s, "\n", `\n`, -1)
+}
+
+func mermaidEscape(s string) string {
+	s = strings.Replace(s, "&", "#amp;", -1)
+	s = strings.Replace(s, `"`, "#quot;", -1)
+	s = strings.Replace(s, "<", "#lt;", -1)
+	return strings.Replace(s, ">", "#gt;", -1)
+}
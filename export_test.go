@@ -0,0 +1,95 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func testExportExpression() Expression {
+	l, c := 1, 2
+	return NewExprIf(
+		NewExprCompareMust(CTEqual, NewExprHeapReference("state", "state", l, c),
+			NewExprConstant(NewExprValueString("ok"), l, c), l, c),
+		NewExprConstant(NewExprValueInteger(1), l, c),
+		NewExprConstant(NewExprValueInteger(2), l, c), l, c)
+}
+
+func TestExportDOT(t *testing.T) {
+	expected := `digraph expression {
+  ordering=out;
+  node [shape=box, fontname="monospace"];
+  n0 [label="if\n{integer}"];
+  n1 [label="compare ==\n{boolean}"];
+  n2 [label="reference state\n{string}"];
+  n1 -> n2;
+  n3 [label="constant \"ok\"\n{string}"];
+  n1 -> n3;
+  n0 -> n1;
+  n4 [label="constant 1\n{integer}"];
+  n0 -> n4;
+  n5 [label="constant 2\n{integer}"];
+  n0 -> n5;
+}
+`
+	dot := ExportDOT(testExportExpression(), ExportOptions{})
+	if dot != expected {
+		t.Errorf("wrong DOT export.\nactual:\n%s\nexpected:\n%s", dot, expected)
+	}
+}
+
+func TestExportMermaid(t *testing.T) {
+	op := testExportExpression()
+	trace := NewEvaluationTrace()
+	inst, err := trace.Instrument(op)
+	if err != nil {
+		t.Errorf("unexpected instrument error: %v", err)
+		return
+	}
+	_, err = inst.Evaluate(newTestRequestContext(map[string]string{"state": "ok"}))
+	if err != nil {
+		t.Errorf("unexpected evaluation error: %v", err)
+		return
+	}
+	expected := `flowchart TD
+  n0["if<br/>{integer}<br/>= 1"]
+  n1["compare ==<br/>{boolean}<br/>= true"]
+  n2["reference state<br/>{string}<br/>= #quot;ok#quot;"]
+  n1 --> n2
+  n3["constant #quot;ok#quot;<br/>{string}<br/>= #quot;ok#quot;"]
+  n1 --> n3
+  n0 --> n1
+  n4["constant 1<br/>{integer}<br/>= 1"]
+  n0 --> n4
+  n5["constant 2<br/>{integer}"]
+  n0 --> n5
+`
+	mermaid := ExportMermaid(op, ExportOptions{Trace: trace})
+	if mermaid != expected {
+		t.Errorf("wrong Mermaid export.\nactual:\n%s\nexpected:\n%s", mermaid, expected)
+	}
+}
+
+func TestExprDescription(t *testing.T) {
+	l, c := 1, 2
+	tests := []struct {
+		name        string
+		op          Expression
+		description string
+	}{
+		{"assign", NewExprAssign("ref", "my/ref", NewExprConstant(EvBooleanTrue, l, c), nil, RSHeap, l, c),
+			"assign my/ref"},
+		{"logical", NewExprLogicalUnary(LTNot, NewExprConstant(EvBooleanTrue, l, c), l, c), "not"},
+		{"logicalThreeValued", NewExprLogicalMode(LTAnd, LMThreeValued, NewExprConstant(EvBooleanTrue, l, c),
+			NewExprConstant(EvBooleanTrue, l, c), l, c), "and3"},
+		{"search", NewExprSearch(NewExprConstant(EvStringEmpty, l, c), NewExprConstant(EvStringEmpty, l, c),
+			nil, STFindAll, NewCompositeTypeSignature(VTList, TsDefault), l, c), "find all"},
+		{"sequence", NewExprSequence([]Expression{NewExprConstant(EvBooleanTrue, l, c)}, l, c), "sequence"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			description := exprDescription(test.op)
+			if description != test.description {
+				t.Errorf("wrong description.\nactual:   %s\nexpected: %s", description, test.description)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatOptions holds the options used when formatting an expression.
+type FormatOptions struct {
+	// Maximum line width. An expression that doesn't fit on the current line is broken into multiple lines.
+	// Note that an expression without sub-expressions (e.g. a constant) is never broken and may therefore exceed
+	// the maximum line width.
+	Width int
+	// Number of spaces used for each indentation level.
+	Indent int
+}
+
+// DefaultFormatOptions is the default options used when formatting an expression.
+var DefaultFormatOptions = FormatOptions{
+	Width:  80,
+	Indent: 2,
+}
+
+// Format returns an indented, line-wrapped, string representation of the expression.
+// The expression is only broken into multiple lines where the compact string representation (see
+// Expression.String()) has a single space or after the comma separating the values of a list or map literal.
+// Replacing each line break (including the indentation of the following line) with a single space (or with
+// nothing after such a comma) therefore gives the compact string representation of the expression.
+// The output is stable. That is the same expression is always formatted the same way.
+func Format(expr Expression, opts FormatOptions) string {
+	f := formatter{opts: opts}
+	f.format(expr, 0, 0)
+	return f.sb.String()
+}
+
+// formatPart is a part of a broken expression. A part consists of an optional sub-expression surrounded by a
+// prefix and a suffix.
+type formatPart struct {
+	prefix string
+	expr   Expression
+	suffix string
+	// If true the part directly follows the previous part (without a space) in the compact layout
+	joined bool
+}
+
+func (fp formatPart) String() string {
+	if fp.expr == nil {
+		return fp.prefix + fp.suffix
+	}
+	return fp.prefix + fp.expr.String() + fp.suffix
+}
+
+// formatLayout describes how an expression may be broken into multiple lines. If the expression is broken each part
+// (except the first) is written on a separate line. The compact layout (parts separated by a single space unless
+// joined) must be equal to the compact string representation of the expression.
+type formatLayout struct {
+	open  string
+	parts []formatPart
+	close string
+}
+
+func (fl formatLayout) String() string {
+	var sb strings.Builder
+	sb.WriteString(fl.open)
+	for i, part := range fl.parts {
+		if i > 0 && !part.joined {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(part.String())
+	}
+	sb.WriteString(fl.close)
+	return sb.String()
+}
+
+type formatter struct {
+	opts FormatOptions
+	sb   strings.Builder
+	// The current column
+	col int
+}
+
+// format writes the expression starting at the current column. Indent is the indentation of the current line and
+// trailing is the number of characters that will follow the expression on the same line.
+func (f *formatter) format(expr Expression, indent, trailing int) {
+	str := expr.String()
+	layout, ok := layoutOf(expr)
+	// Keep the expression on a single line if it fits or if it may not be broken
+	if !ok || f.col+utf8.RuneCountInString(str)+trailing <= f.opts.Width ||
+		layout.String() != str {
+		f.write(str)
+		return
+	}
+	// The parts of a broken expression are indented one level. Sub-expressions that are broken are indented one
+	// more level to distinguish them from the parts of the expression.
+	partIndent := indent + f.opts.Indent
+	f.write(layout.open)
+	for i, part := range layout.parts {
+		if i > 0 {
+			f.newline(partIndent)
+		}
+		partTrailing := utf8.RuneCountInString(part.suffix)
+		if i == len(layout.parts)-1 {
+			partTrailing += utf8.RuneCountInString(layout.close) + trailing
+		}
+		f.write(part.prefix)
+		if part.expr != nil {
+			f.format(part.expr, partIndent, partTrailing)
+		}
+		f.write(part.suffix)
+	}
+	f.write(layout.close)
+}
+
+func (f *formatter) write(s string) {
+	f.sb.WriteString(s)
+	f.col += utf8.RuneCountInString(s)
+}
+
+func (f *formatter) newline(indent int) {
+	f.sb.WriteString("\n")
+	f.sb.WriteString(strings.Repeat(" ", indent))
+	f.col = indent
+}
+
+// layoutOf returns the layout for a broken expression. If the expression may not be broken false is returned.
+func layoutOf(expr Expression) (formatLayout, bool) {
+	switch op := expr.(type) {
+	case *exprAssign:
+		switch op.source {
+		case RSHeap:
+			return formatLayout{"(", []formatPart{
+				{prefix: fmt.Sprintf("%v =", op.key)},
+				{expr: op.valueOp},
+			}, ")"}, true
+		case RSValue:
+			return formatLayout{"(", []formatPart{
+				{expr: op.sourceOp, suffix: fmt.Sprintf(".%v =", op.key)},
+				{expr: op.valueOp},
+			}, ")"}, true
+		}
+	case *exprCompare:
+		return formatLayout{"(", []formatPart{
+			{expr: op.opLeft},
+			{prefix: CompareTypeToString(op.ct) + " ", expr: op.opRight},
+		}, ")"}, true
+	case *exprFor:
+		parts := []formatPart{{prefix: "foreach " + op.key + " in ", expr: op.opList}}
+		if op.opBreak != nil {
+			parts = append(parts, formatPart{prefix: "break on ", expr: op.opBreak})
+		}
+		parts = append(parts, formatPart{prefix: "do ", expr: op.opLoop})
+		return formatLayout{"(", parts, ")"}, true
+	case *exprLet:
+		parts := make([]formatPart, 0, len(op.bindings)+1)
+		for i, binding := range op.bindings {
+			part := formatPart{prefix: binding.Name + " = ", expr: binding.Value}
+			if i == 0 {
+				part.prefix = "let " + part.prefix
+			}
+			if i < len(op.bindings)-1 {
+				part.suffix = ","
+			}
+			parts = append(parts, part)
+		}
+		parts = append(parts, formatPart{prefix: "in ", expr: op.opBody})
+		return formatLayout{"(", parts, ")"}, true
+	case *exprTypeTest:
+		return formatLayout{"(", []formatPart{{expr: op.op, suffix: op.suffix()}}, ")"}, true
+	case *exprTry:
+		return formatLayout{"(", []formatPart{
+			{prefix: "try ", expr: op.opTry},
+			{prefix: "catch " + op.catchClause(), expr: op.opCatch},
+		}, ")"}, true
+	case *exprLambda:
+		parts := []formatPart{{prefix: string(op.lt) + " " + op.key + " in ", expr: op.opList}}
+		if op.lt == LATReduce {
+			parts = append(parts, formatPart{prefix: "with " + op.accKey + " = ", expr: op.opInit})
+		}
+		parts = append(parts, formatPart{prefix: "do ", expr: op.opBody})
+		return formatLayout{"(", parts, ")"}, true
+	case *exprSort:
+		parts := []formatPart{{prefix: op.sortPrefix(), expr: op.opList}}
+		if op.opKey != nil {
+			parts = append(parts, formatPart{prefix: "by ", expr: op.opKey})
+		}
+		return formatLayout{"(", parts, ")"}, true
+	case *exprSwitch:
+		parts := []formatPart{{prefix: "switch"}}
+		if op.opSubject != nil {
+			parts[0].prefix += " "
+			if op.key != "" {
+				parts[0].prefix += op.key + " = "
+			}
+			parts[0].expr = op.opSubject
+		}
+		for _, arm := range op.arms {
+			parts = append(parts, formatPart{prefix: armTestString(arm) + " then ", expr: arm.Result})
+		}
+		if op.opDefault != nil {
+			parts = append(parts, formatPart{prefix: "default ", expr: op.opDefault})
+		}
+		return formatLayout{"(", parts, ")"}, true
+	case *exprIf:
+		parts := []formatPart{
+			{prefix: "if ", expr: op.checkOp},
+			{prefix: "then ", expr: op.thenOp},
+		}
+		if op.elseOp != nil {
+			parts = append(parts, formatPart{prefix: "else ", expr: op.elseOp})
+		}
+		return formatLayout{"(", parts, ")"}, true
+	case *exprLogical:
+		if op.lt == LTNot {
+			return formatLayout{"(", []formatPart{{prefix: op.operator() + " ", expr: op.opLeft}}, ")"}, true
+		}
+		return formatLayout{"(", []formatPart{
+			{expr: op.opLeft},
+			{prefix: op.operator() + " ", expr: op.opRight},
+		}, ")"}, true
+	case *exprReference:
+		if op.source == RSValue {
+			// The reference itself is written directly after the source expression
+			suffix := strings.TrimPrefix(op.String(), op.sourceOp.String())
+			return formatLayout{"", []formatPart{{expr: op.sourceOp, suffix: suffix}}, ""}, true
+		}
+	case *exprSearch:
+		prefix := map[SearchType]string{STExist: "exist ", STFind: "find ", STFindAll: "find all "}[op.searchType]
+		parts := []formatPart{
+			{prefix: prefix, expr: op.opKey},
+			{prefix: "in ", expr: op.opColl},
+		}
+		if op.opDef != nil {
+			parts = append(parts, formatPart{prefix: "default ", expr: op.opDef})
+		}
+		return formatLayout{"(", parts, ")"}, true
+	case *exprAggregate:
+		return functionLayout(string(op.af), []Expression{op.opList})
+	case *exprCall:
+		return functionLayout(op.f.Name, op.args)
+	case *exprCast:
+		return functionLayout(string(op.cf), []Expression{op.op})
+	case *exprDecimal:
+		return functionLayout(op.name(), op.args)
+	case *exprList:
+		return literalLayout("[", nil, op.ops, "]")
+	case *exprMap:
+		return literalLayout("{", op.keys, op.ops, "}")
+	case *exprMapFunction:
+		return functionLayout(op.name(), op.args)
+	case *exprRegexp:
+		return functionLayout(string(op.rf), op.args)
+	case *exprSet:
+		return functionLayout(string(op.so), op.args)
+	case *exprString:
+		return functionLayout(string(op.sf), op.args)
+	case *exprNil:
+		if op.no != NOCoalesce {
+			return functionLayout(string(op.no), op.ops)
+		}
+		parts := make([]formatPart, 0, len(op.ops))
+		for i, subOp := range op.ops {
+			part := formatPart{expr: subOp}
+			if i > 0 {
+				part.prefix = "?? "
+			}
+			parts = append(parts, part)
+		}
+		return formatLayout{"(", parts, ")"}, true
+	case *exprSequence:
+		parts := make([]formatPart, 0, len(op.ops))
+		for _, subOp := range op.ops {
+			parts = append(parts, formatPart{expr: subOp})
+		}
+		return formatLayout{"{", parts, "}"}, true
+	}
+	return formatLayout{}, false
+}
+
+// functionLayout returns the layout for a function like expression, "(name arg1 arg2 ...)".
+func functionLayout(name string, args []Expression) (formatLayout, bool) {
+	if len(args) == 0 {
+		return formatLayout{}, false
+	}
+	parts := make([]formatPart, 0, len(args))
+	for i, arg := range args {
+		if i == 0 {
+			parts = append(parts, formatPart{prefix: name + " ", expr: arg})
+			continue
+		}
+		parts = append(parts, formatPart{expr: arg})
+	}
+	return formatLayout{"(", parts, ")"}, true
+}
+
+// literalLayout returns the layout of a list (no keys) or map literal where each value (prefixed by its key) is a
+// part. The parts are joined by a comma (e.g. "[a,b]" or "{a:1,b:2}") and a broken literal is broken after each
+// comma.
+func literalLayout(open string, keys []string, ops []Expression, close string) (formatLayout, bool) {
+	if len(ops) == 0 {
+		return formatLayout{}, false
+	}
+	parts := make([]formatPart, 0, len(ops))
+	for i, op := range ops {
+		part := formatPart{expr: op, suffix: ",", joined: i > 0}
+		if keys != nil {
+			part.prefix = keys[i] + ":"
+		}
+		if i == len(ops)-1 {
+			part.suffix = ""
+		}
+		parts = append(parts, part)
+	}
+	return formatLayout{open, parts, close}, true
+}
@@ -0,0 +1,117 @@
+package goexpr
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func testFormatExpression() Expression {
+	l, c := 1, 2
+	return NewExprSequence([]Expression{
+		NewExprAssign("result", "result", NewExprConstant(NewExprValueString("unknown"), l, c), nil, RSHeap, l, c),
+		NewExprFor(
+			NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+				NewExprValueString("foo"),
+			}), l, c),
+			NewExprIf(
+				NewExprLogical(LTAnd,
+					NewExprCompareMust(CTMatch, NewExprHeapReference("item", "item", l, c),
+						NewExprConstant(NewExprValueString("^[a-z]+$"), l, c), l, c),
+					NewExprSearch(
+						NewExprHeapReference("item", "item", l, c),
+						NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+							NewExprValueString("foo"),
+							NewExprValueString("bar"),
+						}), l, c),
+						nil, STExist, NewScalarTypeSignature(VTBoolean), l, c), l, c),
+				NewExprAssign("result", "result", NewExprHeapReference("item", "item", l, c), nil, RSHeap, l, c),
+				NewExprConstant(NewExprValueString("no match"), l, c), l, c),
+			nil,
+			"item", l, c),
+	}, l, c)
+}
+
+func TestFormat(t *testing.T) {
+	l, c := 1, 2
+	tests := []struct {
+		name string
+		op   Expression
+		opts FormatOptions
+		str  string
+	}{
+		{"fits", NewExprCompareMust(CTEqual, NewExprHeapReference("state", "state", l, c),
+			NewExprConstant(NewExprValueString("succeeded"), l, c), l, c), DefaultFormatOptions,
+			`(state == "succeeded")`},
+		{"constant", NewExprConstant(NewExprValueString("a long string constant"), l, c), FormatOptions{10, 2},
+			`"a long string constant"`},
+		{"broken", NewExprCompareMust(CTEqual, NewExprHeapReference("state", "state", l, c),
+			NewExprConstant(NewExprValueString("succeeded"), l, c), l, c), FormatOptions{10, 2},
+			"(state\n  == \"succeeded\")"},
+		{"nested", testFormatExpression(), DefaultFormatOptions, `{(result = "unknown")
+  (foreach item in ["foo"]
+    do (if ((item match "^[a-z]+$") and (exist item in ["foo","bar"]))
+      then (result = item)
+      else "no match"))}`},
+		{"nestedNarrow", testFormatExpression(), FormatOptions{40, 4}, `{(result = "unknown")
+    (foreach item in ["foo"]
+        do (if ((item match "^[a-z]+$")
+                and (exist item
+                    in ["foo","bar"]))
+            then (result = item)
+            else "no match"))}`},
+		{"logicalTwoValued", NewExprLogicalMode(LTAnd, LMTwoValued, NewExprHeapReference("enabled", "enabled", l, c),
+			NewExprHeapReference("visible", "visible", l, c), l, c), FormatOptions{20, 2},
+			"(enabled\n  and2 visible)"},
+		{"logicalThreeValued", NewExprLogicalMode(LTNot, LMThreeValued, NewExprHeapReference("enabled", "enabled", l, c),
+			nil, l, c), FormatOptions{10, 2},
+			"(not3 enabled)"},
+		{"list", NewExprListMust([]Expression{
+			NewExprHeapReference("email", "email", l, c),
+			NewExprConstant(NewExprValueString("foo@bar.com"), l, c)}, l, c), FormatOptions{20, 2},
+			"[email,\n  \"foo@bar.com\"]"},
+		{"map", NewExprMapMust([]string{"name", "email"}, []Expression{
+			NewExprHeapReference("name", "name", l, c),
+			NewExprHeapReference("email", "email", l, c)}, l, c), FormatOptions{20, 2},
+			"{email:email,\n  name:name}"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := Format(test.op, test.opts)
+			if str != test.str {
+				t.Errorf("wrong formatted result.\nactual:\n%v\nexpected:\n%v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestFormat_RoundTrip(t *testing.T) {
+	l, c := 1, 2
+	op := testFormatExpression()
+	literal := NewExprMapMust([]string{"to", "cc"}, []Expression{
+		NewExprListMust([]Expression{
+			NewExprHeapReference("email", "email", l, c),
+			NewExprConstant(NewExprValueString("foo@bar.com"), l, c)}, l, c),
+		NewExprListMust([]Expression{
+			NewExprStringMust(SFLower, []Expression{NewExprHeapReference("manager", "manager", l, c)}, l, c)}, l, c),
+	}, l, c)
+	lineBreak := regexp.MustCompile("\n *")
+	commaBreak := regexp.MustCompile(",\n *")
+	for width := 0; width <= 120; width += 5 {
+		str := Format(literal, FormatOptions{Width: width, Indent: 2})
+		if lineBreak.ReplaceAllString(commaBreak.ReplaceAllString(str, ","), " ") != literal.String() {
+			t.Errorf("formatted literal (width %d) doesn't round-trip.\nformatted:\n%v\nexpected: %v",
+				width, str, literal.String())
+		}
+		str = Format(op, FormatOptions{Width: width, Indent: 2})
+		if lineBreak.ReplaceAllString(str, " ") != op.String() {
+			t.Errorf("formatted expression (width %d) doesn't round-trip.\nformatted:\n%v\nexpected: %v",
+				width, str, op.String())
+		}
+		for _, line := range strings.Split(str, "\n") {
+			if width >= 60 && len(line) > width {
+				t.Errorf("line exceeds width %d: %s", width, line)
+			}
+		}
+	}
+}
@@ -77,6 +77,20 @@ const (
 	LTNot LogicalType = "not"
 )
 
+// Logic mode used by logical expressions. The logic mode specifies how nil operands are handled.
+// LMPropagate: the result is nil if an evaluated operand is nil (default).
+// LMThreeValued: nil is "unknown" (Kleene logic). That is "false and nil" is false and "true or nil" is true.
+// LMTwoValued: nil is treated as false. The result is never nil.
+// In the string representation of a logical expression the operator is suffixed by 3 for LMThreeValued and 2 for
+// LMTwoValued (e.g. "and3" and "not2").
+type LogicMode string
+
+const (
+	LMPropagate   LogicMode = ""
+	LMThreeValued LogicMode = "threeValued"
+	LMTwoValued   LogicMode = "twoValued"
+)
+
 // Search Expression type
 type SearchType string
 
@@ -103,6 +117,12 @@ type Expression interface {
 	ExpectedResultType(rt TypeSignature) bool
 	// String returns a compact string representation of the expression. The string is mainly used in tests.
 	String() string
+	// Children returns the sub-expressions of the expression in evaluation order. Optional sub-expressions
+	// that are not specified (e.g. a missing else-Expression) are not included.
+	Children() []Expression
+	// WithChildren returns a copy of the expression where the sub-expressions are replaced by the specified
+	// expressions. The sub-expressions must be specified in the same order (and number) as returned by Children().
+	WithChildren(children []Expression) (Expression, error)
 }
 
 func newBaseExpression(rt TypeSignature, line, col int) baseExpression {
@@ -140,6 +160,41 @@ func (bo baseExpression) nilResult() Value {
 	return NewNilExprValue(bo.resType)
 }
 
+// checkArguments checks that the argument expressions have the expected result types. An argument that is a
+// "dynamically typed" expression (e.g. reference) is adapted to the expected type.
+func checkArguments(name string, args []Expression, params []TypeSignature) error {
+	if len(args) != len(params) {
+		return fmt.Errorf("%s expects %d arguments (got %d)", name, len(params), len(args))
+	}
+	for i, arg := range args {
+		if !arg.ExpectedResultType(params[i]) {
+			return fmt.Errorf("argument %d of %s must be of type %v (got %v)", i+1, name, params[i], arg.ResultType())
+		}
+	}
+	return nil
+}
+
+// functionString returns the string representation of a function like expression, "(name arg1 arg2 ...)".
+func functionString(name string, args []Expression) string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString(name)
+	for _, arg := range args {
+		sb.WriteString(" ")
+		sb.WriteString(arg.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// checkChildren returns an error if the number of sub-expressions is not the expected number of sub-expressions.
+func checkChildren(children []Expression, expected int) error {
+	if len(children) != expected {
+		return fmt.Errorf("expected %d sub-expressions (got %d)", expected, len(children))
+	}
+	return nil
+}
+
 // exprAssign assigns a value to a reference in the request context reference heap or a referable value (struct).
 // The result of the Expression is the value assigned (including nil).
 // Source specifies the reference type (heap or referable variable). Index is the reference index to read. If source is
@@ -208,6 +263,26 @@ func (op *exprAssign) String() string {
 	return sb.String()
 }
 
+func (op *exprAssign) Children() []Expression {
+	if op.source == RSValue {
+		return []Expression{op.valueOp, op.sourceOp}
+	}
+	return []Expression{op.valueOp}
+}
+
+func (op *exprAssign) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.Children()))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.valueOp = children[0]
+	if op.source == RSValue {
+		cp.sourceOp = children[1]
+	}
+	return &cp, nil
+}
+
 func NewExprAssign(name string, key interface{}, valueOp, sourceOp Expression, source ReferenceSource, line, col int) Expression {
 	return &exprAssign{
 		baseExpression: newBaseExpression(valueOp.ResultType(), line, col),
@@ -225,6 +300,8 @@ func NewExprAssign(name string, key interface{}, valueOp, sourceOp Expression, s
 // If one of the operands is a nil value the following applies.
 // For equal and non-equal nil == nil and nil != non-nil.
 // In other case the result is nil. That is the compare Expression propagates nil.
+// Less and greater comparisons are supported for comparable types (see Value.Compare()). Strings are compared
+// using the collator if specified (otherwise byte-wise).
 type exprCompare struct {
 	baseExpression
 	//	ruleCtx *ruleContext
@@ -232,6 +309,8 @@ type exprCompare struct {
 	ct      CompareType
 	opLeft  Expression
 	opRight Expression
+	// Collator used when comparing strings (may be nil)
+	collator Collator
 }
 
 func (op *exprCompare) Evaluate(recCtx RequestContext) (Value, error) {
@@ -258,13 +337,13 @@ func (op *exprCompare) Evaluate(recCtx RequestContext) (Value, error) {
 
 	switch op.ct {
 	case CTLess:
-		return NewExprValueBoolean(resLeft.Compare(resRight).Value.(int) < 0), nil
+		return NewExprValueBoolean(resLeft.CompareCollated(resRight, op.collator).Value.(int) < 0), nil
 	case CTLessEqual:
-		return NewExprValueBoolean(resLeft.Compare(resRight).Value.(int) <= 0), nil
+		return NewExprValueBoolean(resLeft.CompareCollated(resRight, op.collator).Value.(int) <= 0), nil
 	case CTGreater:
-		return NewExprValueBoolean(resLeft.Compare(resRight).Value.(int) > 0), nil
+		return NewExprValueBoolean(resLeft.CompareCollated(resRight, op.collator).Value.(int) > 0), nil
 	case CTGreaterEqual:
-		return NewExprValueBoolean(resLeft.Compare(resRight).Value.(int) >= 0), nil
+		return NewExprValueBoolean(resLeft.CompareCollated(resRight, op.collator).Value.(int) >= 0), nil
 	case CTMatch:
 		// <string> match <regexp>
 		check, err := op.opLeft.Evaluate(recCtx)
@@ -294,9 +373,33 @@ func (op *exprCompare) String() string {
 	return sb.String()
 }
 
+func (op *exprCompare) Children() []Expression {
+	return []Expression{op.opLeft, op.opRight}
+}
+
+func (op *exprCompare) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, 2)
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.opLeft, cp.opRight = children[0], children[1]
+	return &cp, nil
+}
+
+// NewExprCompare creates a compare Expression. An error is returned if a less or greater comparison is done on
+// operands having different or non-comparable result types.
 func NewExprCompare(ct CompareType, leftOp Expression, rightOp Expression, line, col int) (Expression, error) {
-	// If match compare and matcher is a constant string convert to constant regexp
-	if ct == CTMatch {
+	return NewExprCompareCollated(ct, leftOp, rightOp, nil, line, col)
+}
+
+// NewExprCompareCollated creates a compare Expression where strings are compared using the specified collator
+// (e.g. a locale-aware collator). If the collator is nil strings are compared byte-wise.
+func NewExprCompareCollated(ct CompareType, leftOp Expression, rightOp Expression, collator Collator,
+	line, col int) (Expression, error) {
+	switch ct {
+	case CTMatch:
+		// If match compare and matcher is a constant string convert to constant regexp
 		constant, ok := rightOp.(*exprConstant)
 		if ok && constant.ResultType().IsValueType(VTString) {
 			str := constant.c.Value.(string)
@@ -306,6 +409,13 @@ func NewExprCompare(ct CompareType, leftOp Expression, rightOp Expression, line,
 			}
 			rightOp = NewExprConstant(regexp, line, col)
 		}
+	case CTLess, CTLessEqual, CTGreater, CTGreaterEqual:
+		if !leftOp.ExpectedResultType(rightOp.ResultType()) && !rightOp.ExpectedResultType(leftOp.ResultType()) {
+			return nil, fmt.Errorf("can't compare %v with %v", leftOp.ResultType(), rightOp.ResultType())
+		}
+		if !VTMetadata.ComparableType(leftOp.ResultType()) {
+			return nil, fmt.Errorf("type %v is not comparable", leftOp.ResultType())
+		}
 	}
 
 	return &exprCompare{
@@ -313,6 +423,7 @@ func NewExprCompare(ct CompareType, leftOp Expression, rightOp Expression, line,
 		ct:             ct,
 		opLeft:         leftOp,
 		opRight:        rightOp,
+		collator:       collator,
 	}, nil
 }
 
@@ -339,6 +450,19 @@ func (op *exprConstant) String() string {
 	return op.c.String()
 }
 
+func (op *exprConstant) Children() []Expression {
+	return nil
+}
+
+func (op *exprConstant) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, 0)
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	return &cp, nil
+}
+
 func NewExprConstant(c Value, line, col int) Expression {
 	return &exprConstant{
 		baseExpression: newBaseExpression(c.Type, line, col),
@@ -357,12 +481,26 @@ func (op *exprError) String() string {
 	return "<<error>>"
 }
 
+func (op *exprError) Children() []Expression {
+	return nil
+}
+
+func (op *exprError) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, 0)
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	return &cp, nil
+}
+
 func (op *exprError) Evaluate(_ RequestContext) (Value, error) {
 	return NewNilExprValue(op.resType), fmt.Errorf("exprError")
 }
 
 // exprFor represent a for loop.
 // The loop Expression is applied one time for every value from the result of the list Expression (the result must be a list).
+// If the result of the list Expression is a map the loop Expression is applied to the map values in key order.
 // The reference specified by the heap index is set to the value in each iteration so that the loop Expression may reference
 // the current list value.
 // The result of the for Expression is the result from the last execution of the loop Expression. That is the loop Expression
@@ -390,7 +528,11 @@ func (op *exprFor) Evaluate(recCtx RequestContext) (Value, error) {
 		return op.nilResult(), err
 	}
 	// If no value to loop on (empty or nil list) we return a nil value
-	if list.Nil() || len(list.Value.([]Value)) == 0 {
+	if list.Nil() {
+		return op.nilResult(), nil
+	}
+	values := list.iterationValues()
+	if len(values) == 0 {
 		return op.nilResult(), nil
 	}
 	// Compute break value if break Expression exist
@@ -402,8 +544,8 @@ func (op *exprFor) Evaluate(recCtx RequestContext) (Value, error) {
 		}
 	}
 	var res Value
-	// The compiler should have checked that the list is a list.
-	for _, value := range list.Value.([]Value) {
+	// The compiler should have checked that the list is iterable.
+	for _, value := range values {
 		err := recCtx.Assign(op.key, value)
 		if err != nil {
 			return op.nilResult(), err
@@ -437,6 +579,26 @@ func (op *exprFor) String() string {
 	return sb.String()
 }
 
+func (op *exprFor) Children() []Expression {
+	if op.opBreak != nil {
+		return []Expression{op.opList, op.opBreak, op.opLoop}
+	}
+	return []Expression{op.opList, op.opLoop}
+}
+
+func (op *exprFor) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.Children()))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.opList, cp.opLoop = children[0], children[len(children)-1]
+	if op.opBreak != nil {
+		cp.opBreak = children[1]
+	}
+	return &cp, nil
+}
+
 func NewExprFor(opList Expression, opLoop Expression, opBreak Expression, key string, line, col int) Expression {
 	return &exprFor{
 		baseExpression: newBaseExpression(*opList.ResultType().UnitType, line, col),
@@ -505,6 +667,26 @@ func (op *exprIf) String() string {
 	return sb.String()
 }
 
+func (op *exprIf) Children() []Expression {
+	if op.elseOp != nil {
+		return []Expression{op.checkOp, op.thenOp, op.elseOp}
+	}
+	return []Expression{op.checkOp, op.thenOp}
+}
+
+func (op *exprIf) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.Children()))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.checkOp, cp.thenOp = children[0], children[1]
+	if op.elseOp != nil {
+		cp.elseOp = children[2]
+	}
+	return &cp, nil
+}
+
 func NewExprIf(checkOp Expression, thenOp Expression, elseOp Expression, line, col int) Expression {
 	return &exprIf{
 		baseExpression: newBaseExpression(thenOp.ResultType(), line, col),
@@ -517,14 +699,15 @@ func NewExprIf(checkOp Expression, thenOp Expression, elseOp Expression, line, c
 // exprLogical represent a logical expression.
 // Note that lazy evaluation is used. That is if opLeft returns false for "and" then opRight will not be
 // evaluated and if opLeft evaluates to true for "or" then opRight will not be evaluated.
-// If left or right operand evaluates to nil the the result of the logical Expression is nil. That is
-// nil is propagated.
+// How nil operands are handled is given by the logic mode (see LogicMode). Default is LMPropagate where the result
+// of the logical Expression is nil if left or right operand evaluates to nil. That is nil is propagated.
 type exprLogical struct {
 	baseExpression
 	//	ruleCtx *ruleContext
 	lt      LogicalType
 	opLeft  Expression
 	opRight Expression
+	mode    LogicMode
 }
 
 func (op *exprLogical) Evaluate(recCtx RequestContext) (Value, error) {
@@ -532,6 +715,12 @@ func (op *exprLogical) Evaluate(recCtx RequestContext) (Value, error) {
 	if err != nil {
 		return EvNilBoolean, err
 	}
+	switch op.mode {
+	case LMThreeValued:
+		return op.evaluateThreeValued(recCtx, resLeft)
+	case LMTwoValued:
+		return op.evaluateTwoValued(recCtx, resLeft)
+	}
 	if resLeft.Nil() {
 		return op.nilResult(), nil
 	}
@@ -577,26 +766,109 @@ func (op *exprLogical) Evaluate(recCtx RequestContext) (Value, error) {
 	}
 }
 
+// evaluateThreeValued evaluates the logical Expression using three-valued (Kleene) logic. Nil is "unknown".
+// That is "false and nil" is false, "true or nil" is true and all other combinations with nil are nil.
+func (op *exprLogical) evaluateThreeValued(recCtx RequestContext, resLeft Value) (Value, error) {
+	if op.lt == LTNot {
+		if resLeft.Nil() {
+			return op.nilResult(), nil
+		}
+		return NewExprValueBoolean(!resLeft.Value.(bool)), nil
+	}
+	// The value of the left operand giving the result without evaluating the right operand
+	decisive := op.lt == LTOr
+	if !resLeft.Nil() && resLeft.Value.(bool) == decisive {
+		return NewExprValueBoolean(decisive), nil
+	}
+	resRight, err := op.opRight.Evaluate(recCtx)
+	if err != nil {
+		return EvNilBoolean, err
+	}
+	if !resRight.Nil() && resRight.Value.(bool) == decisive {
+		return NewExprValueBoolean(decisive), nil
+	}
+	if resLeft.Nil() || resRight.Nil() {
+		return op.nilResult(), nil
+	}
+	return NewExprValueBoolean(!decisive), nil
+}
+
+// evaluateTwoValued evaluates the logical Expression using two-valued logic where nil is treated as false.
+// The result is never nil.
+func (op *exprLogical) evaluateTwoValued(recCtx RequestContext, resLeft Value) (Value, error) {
+	left := !resLeft.Nil() && resLeft.Value.(bool)
+	switch op.lt {
+	case LTAnd:
+		if !left {
+			return EvBooleanFalse, nil
+		}
+	case LTOr:
+		if left {
+			return EvBooleanTrue, nil
+		}
+	case LTNot:
+		return NewExprValueBoolean(!left), nil
+	default:
+		panic(fmt.Sprintf("unknown logial Expression type %v", op.lt))
+	}
+	resRight, err := op.opRight.Evaluate(recCtx)
+	if err != nil {
+		return EvNilBoolean, err
+	}
+	return NewExprValueBoolean(!resRight.Nil() && resRight.Value.(bool)), nil
+}
+
+// operator returns the operator of the logical Expression including the logic mode (e.g. "and" or "and3")
+func (op *exprLogical) operator() string {
+	switch op.mode {
+	case LMThreeValued:
+		return string(op.lt) + "3"
+	case LMTwoValued:
+		return string(op.lt) + "2"
+	default:
+		return string(op.lt)
+	}
+}
+
 func (op *exprLogical) String() string {
 	var sb strings.Builder
 	sb.WriteString("(")
 	switch op.lt {
-	case LTAnd:
+	case LTAnd, LTOr:
 		sb.WriteString(op.opLeft.String())
-		sb.WriteString(" and ")
-		sb.WriteString(op.opRight.String())
-	case LTOr:
-		sb.WriteString(op.opLeft.String())
-		sb.WriteString(" or ")
+		sb.WriteString(" ")
+		sb.WriteString(op.operator())
+		sb.WriteString(" ")
 		sb.WriteString(op.opRight.String())
 	case LTNot:
-		sb.WriteString("not ")
+		sb.WriteString(op.operator())
+		sb.WriteString(" ")
 		sb.WriteString(op.opLeft.String())
 	}
 	sb.WriteString(")")
 	return sb.String()
 }
 
+func (op *exprLogical) Children() []Expression {
+	if op.opRight != nil {
+		return []Expression{op.opLeft, op.opRight}
+	}
+	return []Expression{op.opLeft}
+}
+
+func (op *exprLogical) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.Children()))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.opLeft = children[0]
+	if op.opRight != nil {
+		cp.opRight = children[1]
+	}
+	return &cp, nil
+}
+
 func NewExprLogical(lt LogicalType, leftOp Expression, rightOp Expression, line, col int) Expression {
 	return &exprLogical{
 		baseExpression: newBaseExpression(NewScalarTypeSignature(VTBoolean), line, col),
@@ -610,12 +882,27 @@ func NewExprLogicalUnary(lt LogicalType, leftOp Expression, line, col int) Expre
 	return NewExprLogical(lt, leftOp, nil, line, col)
 }
 
+// NewExprLogicalMode creates a logical Expression using the specified logic mode. For a unary logical Expression
+// (not) the right operand must be nil.
+func NewExprLogicalMode(lt LogicalType, mode LogicMode, leftOp Expression, rightOp Expression,
+	line, col int) Expression {
+	return &exprLogical{
+		baseExpression: newBaseExpression(NewScalarTypeSignature(VTBoolean), line, col),
+		lt:             lt,
+		opLeft:         leftOp,
+		opRight:        rightOp,
+		mode:           mode,
+	}
+}
+
 // exprReference reads a reference from the request context reference heap or a referable value (struct).
 // The result of the Expression is the value read.
 // Source specifies the reference type (heap or referable variable). Index is the reference index to read. If source is
 // a variable then the variable is the result of the source Expression.
 // If the result of the source Expression is nil then the result of the reference Expression is nil. That is the reference of
 // a nil value is nil.
+// An error reading the request context heap is returned as an EvaluationError (see NewEvaluationError()) of kind
+// EKReference.
 type exprReference struct {
 	baseExpression
 	//	ruleCtx *ruleContext
@@ -633,7 +920,11 @@ func (op *exprReference) Evaluate(recCtx RequestContext) (Value, error) {
 	switch op.source {
 	case RSHeap:
 		// The source of the reference is the request context heap
-		return recCtx.Reference(op.key, op.ResultType())
+		value, err := recCtx.Reference(op.key, op.ResultType())
+		if err != nil {
+			return value, NewEvaluationError(EKReference, err, op.Line(), op.Col())
+		}
+		return value, nil
 	case RSValue:
 		// The source of the reference is a referable value. Currently struct is supported.
 		source, err := op.sourceOp.Evaluate(recCtx)
@@ -656,6 +947,13 @@ func (op *exprReference) String() string {
 		sb.WriteString(fmt.Sprint(op.key))
 	case RSValue:
 		sb.WriteString(op.sourceOp.String())
+		if op.indexed() {
+			// Indexes and slices are written as items[0] and items[1:3]
+			sb.WriteString("[")
+			sb.WriteString(fmt.Sprint(op.key))
+			sb.WriteString("]")
+			break
+		}
 		sb.WriteString(".")
 		sb.WriteString(fmt.Sprint(op.key))
 	default:
@@ -664,16 +962,62 @@ func (op *exprReference) String() string {
 	return sb.String()
 }
 
+func (op *exprReference) Children() []Expression {
+	if op.source == RSValue {
+		return []Expression{op.sourceOp}
+	}
+	return nil
+}
+
+func (op *exprReference) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.Children()))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	if op.source == RSValue {
+		cp.sourceOp = children[0]
+	}
+	return &cp, nil
+}
+
+// indexed returns true if the reference is an index or a slice of a list or a string
+func (op *exprReference) indexed() bool {
+	if op.source != RSValue {
+		return false
+	}
+	switch op.key.(type) {
+	case int, Slice:
+		return true
+	}
+	return false
+}
+
 func (op *exprReference) ExpectedResultType(rt TypeSignature) bool {
+	if !op.adaptable(rt) {
+		return false
+	}
+	op.resType = rt
+	return true
+}
+
+// adaptable returns true if the reference has, or may be transformed to, the result type
+func (op *exprReference) adaptable(rt TypeSignature) bool {
 	if op.ResultType().Equal(rt) {
 		return true
 	}
-	if rt.Scalar() {
-		// A reference may be transformed to a scalar type (other than the current result type)
-		op.resType = rt
-		return true
+	// The result type of an index or a slice is given by the source type. A reference may be transformed to a
+	// scalar type (other than the current result type).
+	return rt.Scalar() && !op.indexed()
+}
+
+// acceptsResultType returns true if the Expression has, or may be adapted to (see ExpectedResultType()), the result
+// type. Unlike ExpectedResultType() the Expression is never adapted.
+func acceptsResultType(op Expression, rt TypeSignature) bool {
+	if ref, ok := op.(*exprReference); ok {
+		return ref.adaptable(rt)
 	}
-	return false
+	return op.ResultType().Equal(rt)
 }
 
 func NewExprHeapReference(name string, key interface{}, line, col int) Expression {
@@ -686,10 +1030,24 @@ func NewExprHeapReference(name string, key interface{}, line, col int) Expressio
 	}
 }
 
+// NewExprTypedHeapReference creates a heap reference with the specified result type. Contrary to
+// NewExprHeapReference() the result type may be a composite type (e.g. a list).
+func NewExprTypedHeapReference(name string, key interface{}, ts TypeSignature, line, col int) Expression {
+	return &exprReference{
+		baseExpression: newBaseExpression(ts, line, col),
+		name:           name,
+		key:            key,
+		source:         RSHeap,
+	}
+}
+
+// NewExprValueReference creates a reference to a sub-value of the result of the source Expression.
+// A string key references a map value. An integer key (index) or a Slice key references a part of a list or a
+// string. For an index the result type is the unit type of a list and string for a string. For a slice the result
+// type is the type of the source.
 func NewExprValueReference(name string, key interface{}, sourceOp Expression, line, col int) Expression {
 	return &exprReference{
-		// As default a reference returns a string
-		baseExpression: newBaseExpression(NewScalarTypeSignature(VTString), line, col),
+		baseExpression: newBaseExpression(valueReferenceType(key, sourceOp.ResultType()), line, col),
 		name:           name,
 		key:            key,
 		sourceOp:       sourceOp,
@@ -697,6 +1055,23 @@ func NewExprValueReference(name string, key interface{}, sourceOp Expression, li
 	}
 }
 
+// valueReferenceType returns the result type of a reference to a sub-value (see NewExprValueReference()) of a value
+// of the source type
+func valueReferenceType(key interface{}, ts TypeSignature) TypeSignature {
+	switch key.(type) {
+	case int:
+		if ts.IsValueType(VTList) {
+			return *ts.UnitType
+		}
+	case Slice:
+		if ts.IsValueType(VTList) {
+			return ts
+		}
+	}
+	// As default a reference returns a string
+	return NewScalarTypeSignature(VTString)
+}
+
 // exprSearch applies a specified search operation on a specified searchable value. The result of the Expression is specific
 // to the search operation.
 // Exist
@@ -802,6 +1177,26 @@ func (op *exprSearch) String() string {
 	return sb.String()
 }
 
+func (op *exprSearch) Children() []Expression {
+	if op.opDef != nil {
+		return []Expression{op.opKey, op.opColl, op.opDef}
+	}
+	return []Expression{op.opKey, op.opColl}
+}
+
+func (op *exprSearch) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.Children()))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.opKey, cp.opColl = children[0], children[1]
+	if op.opDef != nil {
+		cp.opDef = children[2]
+	}
+	return &cp, nil
+}
+
 func NewExprSearch(opKey, opColl, opDef Expression, searchType SearchType, resType TypeSignature, line, col int) Expression {
 	return &exprSearch{
 		baseExpression: newBaseExpression(resType, line, col),
@@ -849,6 +1244,20 @@ func (op *exprSequence) String() string {
 	return sb.String()
 }
 
+func (op *exprSequence) Children() []Expression {
+	return append([]Expression(nil), op.ops...)
+}
+
+func (op *exprSequence) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.ops))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.ops = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
 func NewExprSequence(ops []Expression, line, col int) Expression {
 	return &exprSequence{
 		baseExpression: newBaseExpression(ops[len(ops)-1].ResultType(), line, col), // Empty sequences are not supported
@@ -0,0 +1,189 @@
+package goexpr
+
+import (
+	"fmt"
+)
+
+// Aggregate function type
+type AggregateFunction string
+
+const (
+	AFAvg   AggregateFunction = "avg"
+	AFCount AggregateFunction = "count"
+	AFMax   AggregateFunction = "max"
+	AFMin   AggregateFunction = "min"
+	AFSum   AggregateFunction = "sum"
+)
+
+// numericType holds the arithmetic used by aggregate functions for a numeric value type
+type numericType struct {
+	// The sum of no values
+	zero Value
+	// Add two non-nil values
+	add func(v1, v2 Value) Value
+	// Divide a non-nil value by a positive count
+	div func(v Value, n int) Value
+}
+
+// numericTypes holds the numeric value types (value types you may sum and average)
+var numericTypes = map[ValueType]numericType{
+	VTInteger: {
+		zero: NewExprValueInteger(0),
+		add: func(v1, v2 Value) Value {
+			return NewExprValueInteger(v1.Value.(int) + v2.Value.(int))
+		},
+		div: func(v Value, n int) Value {
+			return NewExprValueInteger(v.Value.(int) / n)
+		},
+	},
+	VTDecimal: {
+		zero: NewExprValueDecimal(Decimal{}),
+		add: func(v1, v2 Value) Value {
+			return NewExprValueDecimal(v1.Value.(Decimal).Add(v2.Value.(Decimal)))
+		},
+		div: func(v Value, n int) Value {
+			d := v.Value.(Decimal)
+			avg, _ := d.Quo(NewDecimalFromInt(n), d.Scale(), RMHalfEven)
+			return NewExprValueDecimal(avg)
+		},
+	},
+}
+
+// exprAggregate aggregates the values in a list (the result of the list Expression) using an aggregate function.
+// Nil values in the list are ignored.
+// sum <list> => the sum of the values (0 for an empty list). The list values must be numeric.
+// avg <list> => the average of the values (nil for an empty list). The list values must be numeric. The average
+// of integers is truncated towards zero. The average of decimals has the scale of the sum and is rounded half
+// even (see RMHalfEven).
+// min <list> => the smallest value (nil for an empty list). The list values must be comparable.
+// max <list> => the largest value (nil for an empty list). The list values must be comparable.
+// count <list> => the number of (non-nil) values (0 for an empty list).
+// The result of count is an integer. The result of the other functions has the unit type of the list.
+// To count the values matching a condition use a count lambda (see NewExprCountIf()).
+// If the list is nil then nil is returned. That is the aggregate Expression propagates nil.
+type exprAggregate struct {
+	baseExpression
+	af     AggregateFunction
+	opList Expression
+}
+
+func (op *exprAggregate) Evaluate(recCtx RequestContext) (Value, error) {
+	list, err := op.opList.Evaluate(recCtx)
+	if err != nil {
+		return op.nilResult(), err
+	}
+	if list.Nil() {
+		return op.nilResult(), nil
+	}
+
+	values := make([]Value, 0, len(list.Value.([]Value)))
+	for _, value := range list.Value.([]Value) {
+		if !value.Nil() {
+			values = append(values, value)
+		}
+	}
+	switch op.af {
+	case AFCount:
+		return NewExprValueInteger(len(values)), nil
+	case AFSum, AFAvg:
+		numeric := numericTypes[op.ResultType().BaseType]
+		if len(values) == 0 {
+			if op.af == AFSum {
+				return numeric.zero, nil
+			}
+			return op.nilResult(), nil
+		}
+		sum := numeric.zero
+		for _, value := range values {
+			sum = numeric.add(sum, value)
+		}
+		if op.af == AFAvg {
+			return numeric.div(sum, len(values)), nil
+		}
+		return sum, nil
+	case AFMax, AFMin:
+		if len(values) == 0 {
+			return op.nilResult(), nil
+		}
+		result := values[0]
+		for _, value := range values[1:] {
+			cmp := compareValues(value, result, nil)
+			if (op.af == AFMin && cmp < 0) || (op.af == AFMax && cmp > 0) {
+				result = value
+			}
+		}
+		return result, nil
+	default:
+		panic(fmt.Sprintf("unknown aggregate function %v", op.af))
+	}
+}
+
+func (op *exprAggregate) String() string {
+	return functionString(string(op.af), []Expression{op.opList})
+}
+
+func (op *exprAggregate) Children() []Expression {
+	return []Expression{op.opList}
+}
+
+func (op *exprAggregate) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, 1)
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.opList = children[0]
+	return &cp, nil
+}
+
+// NewExprAggregate creates an aggregate Expression. An error is returned if the aggregate function is unknown,
+// if the list Expression doesn't return a list or if the list values have the wrong type for the aggregate
+// function.
+func NewExprAggregate(af AggregateFunction, opList Expression, line, col int) (Expression, error) {
+	if !opList.ResultType().IsValueType(VTList) {
+		return nil, fmt.Errorf("%s expects a list (got %v)", af, opList.ResultType())
+	}
+	unit := *opList.ResultType().UnitType
+	rt := unit
+	switch af {
+	case AFCount:
+		rt = NewScalarTypeSignature(VTInteger)
+	case AFSum, AFAvg:
+		if _, ok := numericTypes[unit.BaseType]; !ok {
+			return nil, fmt.Errorf("%s expects a list of numeric values (got %v)", af, unit)
+		}
+	case AFMax, AFMin:
+		if !VTMetadata.ComparableType(unit) {
+			return nil, fmt.Errorf("%s expects a list of comparable values (got %v)", af, unit)
+		}
+	default:
+		return nil, fmt.Errorf("unknown aggregate function %v", af)
+	}
+	return &exprAggregate{
+		baseExpression: newBaseExpression(rt, line, col),
+		af:             af,
+		opList:         opList,
+	}, nil
+}
+
+func NewExprAggregateMust(af AggregateFunction, opList Expression, line, col int) Expression {
+	expr, err := NewExprAggregate(af, opList, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating aggregate expression: %v", err))
+	}
+	return expr
+}
+
+// NewExprCountIf creates an Expression counting the values in a list for which a condition (a boolean body
+// Expression) evaluates to true. It is a shorthand for a count lambda Expression (see NewExprLambda()).
+func NewExprCountIf(opList, opCond Expression, key string, line, col int) (Expression, error) {
+	return NewExprLambda(LATCount, opList, opCond, key, line, col)
+}
+
+func NewExprCountIfMust(opList, opCond Expression, key string, line, col int) Expression {
+	expr, err := NewExprCountIf(opList, opCond, key, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating countIf expression: %v", err))
+	}
+	return expr
+}
@@ -0,0 +1,128 @@
+package goexpr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExprAggregate_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	tsIntegerList := NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTInteger))
+	list := func(values ...Value) Expression {
+		return NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), values), l, c)
+	}
+	integer := NewExprValueInteger
+	strs := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+		NewExprValueString("b"), NewExprValueString("c"), NewExprValueString("a")}), l, c)
+	nilList := NewExprConstant(NewNilExprValue(tsIntegerList), l, c)
+	decimals := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTDecimal), []Value{
+		NewExprValueDecimal(ParseDecimalMust("0.5")), NewExprValueDecimal(ParseDecimalMust("0.25")),
+		NewExprValueDecimal(ParseDecimalMust("0.251"))}), l, c)
+	tests := []struct {
+		name   string
+		af     AggregateFunction
+		list   Expression
+		result Value
+	}{
+		{"sum", AFSum, list(integer(1), integer(2), integer(3)), integer(6)},
+		{"sumEmpty", AFSum, list(), integer(0)},
+		{"sumNilValue", AFSum, list(integer(1), EvNilInteger, integer(3)), integer(4)},
+		{"avg", AFAvg, list(integer(1), integer(2), integer(4)), integer(2)},
+		{"avgEmpty", AFAvg, list(), EvNilInteger},
+		{"avgNilValue", AFAvg, list(integer(2), EvNilInteger, integer(4)), integer(3)},
+		{"min", AFMin, list(integer(3), integer(-1), integer(2)), integer(-1)},
+		{"minExtremes", AFMin, list(integer(1), integer(math.MinInt64), integer(math.MaxInt64)),
+			integer(math.MinInt64)},
+		{"maxExtremes", AFMax, list(integer(math.MaxInt64), integer(math.MinInt64), integer(-1)),
+			integer(math.MaxInt64)},
+		{"minEmpty", AFMin, list(), EvNilInteger},
+		{"minOnlyNil", AFMin, list(EvNilInteger), EvNilInteger},
+		{"max", AFMax, list(integer(3), EvNilInteger, integer(7)), integer(7)},
+		{"maxString", AFMax, strs, NewExprValueString("c")},
+		{"count", AFCount, list(integer(3), integer(1)), integer(2)},
+		{"countEmpty", AFCount, list(), integer(0)},
+		{"countNilValue", AFCount, list(integer(3), EvNilInteger), integer(1)},
+		{"countString", AFCount, strs, integer(3)},
+		{"sumDecimal", AFSum, decimals, NewExprValueDecimal(ParseDecimalMust("1.001"))},
+		{"avgDecimal", AFAvg, decimals, NewExprValueDecimal(ParseDecimalMust("0.334"))},
+		{"maxDecimal", AFMax, decimals, NewExprValueDecimal(ParseDecimalMust("0.5"))},
+		// nil ---------------------------------------
+		{"sumNil", AFSum, nilList, EvNilInteger},
+		{"countNil", AFCount, nilList, EvNilInteger},
+		{"maxNil", AFMax, nilList, EvNilInteger},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, err := NewExprAggregate(test.af, test.list, l, c)
+			if err != nil {
+				t.Errorf("unexpected error creating expression: %v", err)
+				return
+			}
+			if !op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", op.ResultType(), test.result.Type)
+			}
+			res, err := op.Evaluate(newEmptyTestRequestContext())
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestExprAggregate_String(t *testing.T) {
+	l, c := 1, 2
+	list := NewExprTypedHeapReference("amounts", "amounts",
+		NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTInteger)), l, c)
+	op := NewExprAggregateMust(AFSum, list, l, c)
+	expected := `(sum amounts)`
+	if op.String() != expected {
+		t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", op.String(), expected)
+	}
+}
+
+func TestNewExprCountIf(t *testing.T) {
+	l, c := 1, 2
+	list := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{
+		NewExprValueInteger(1), NewExprValueInteger(5), NewExprValueInteger(7)}), l, c)
+	ref := NewExprHeapReference("x", "x", l, c)
+	ref.ExpectedResultType(NewScalarTypeSignature(VTInteger))
+	cond := NewExprCompareMust(CTGreater, ref, NewExprConstant(NewExprValueInteger(2), l, c), l, c)
+	op := NewExprCountIfMust(list, cond, "x", l, c)
+	res, err := op.Evaluate(newEmptyTestRequestContext())
+	if err != nil {
+		t.Errorf("unexprected evaluation error: %v", err)
+		return
+	}
+	if !res.Equal(NewExprValueInteger(2)) {
+		t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, 2)
+	}
+}
+
+func TestNewExprAggregate_Error(t *testing.T) {
+	l, c := 1, 2
+	strs := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{}), l, c)
+	regexps := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTRegexp), []Value{}), l, c)
+	tests := []struct {
+		name string
+		af   AggregateFunction
+		list Expression
+	}{
+		{"unknown", AggregateFunction("unknown"), strs},
+		{"notList", AFCount, NewExprConstant(NewExprValueInteger(1), l, c)},
+		{"sumNotNumeric", AFSum, strs},
+		{"avgNotNumeric", AFAvg, strs},
+		{"minNotComparable", AFMin, regexps},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprAggregate(test.af, test.list, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type test operator
+type TypeOperator string
+
+const (
+	TOAs TypeOperator = "as"
+	TOIs TypeOperator = "is"
+)
+
+// exprTypeTest checks the runtime type of the result of an Expression. For an any value the type of the value held
+// by the any value is checked.
+// is: true if the value has the specified type. The result for a nil value is false.
+// as: the value (unwrapped from an any value) if the value has the specified type. If the value has another type
+// an evaluation error of kind EKConversion is returned. If the type is VTAny the value is converted to an any value
+// (see NewExprValueAny()). A nil value is returned as a nil value of the specified type.
+type exprTypeTest struct {
+	baseExpression
+	to TypeOperator
+	op Expression
+	// The type to test for
+	ts TypeSignature
+}
+
+func (op *exprTypeTest) Evaluate(recCtx RequestContext) (Value, error) {
+	value, err := op.op.Evaluate(recCtx)
+	if err != nil {
+		return op.nilResult(), err
+	}
+	if value.Nil() {
+		if op.to == TOIs {
+			return EvBooleanFalse, nil
+		}
+		return op.nilResult(), nil
+	}
+	actual := value
+	if value.Type.IsValueType(VTAny) {
+		actual = value.Value.(Value)
+	}
+	switch op.to {
+	case TOIs:
+		return NewExprValueBoolean(actual.Type.Equal(op.ts)), nil
+	case TOAs:
+		if op.ts.IsValueType(VTAny) {
+			return NewExprValueAny(actual), nil
+		}
+		if !actual.Type.Equal(op.ts) {
+			return op.nilResult(), NewEvaluationError(EKConversion, &ConversionError{
+				Err: fmt.Errorf("%v is of type %s and not %s", actual, actual.Type.Text(), op.ts.Text()),
+			}, op.Line(), op.Col())
+		}
+		return actual, nil
+	default:
+		panic(fmt.Sprintf("unknown type operator %v", op.to))
+	}
+}
+
+// suffix returns the string representation of the type test following the Expression (e.g. " is string")
+func (op *exprTypeTest) suffix() string {
+	return fmt.Sprintf(" %s %s", op.to, op.ts.Text())
+}
+
+func (op *exprTypeTest) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString(op.op.String())
+	sb.WriteString(op.suffix())
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func (op *exprTypeTest) Children() []Expression {
+	return []Expression{op.op}
+}
+
+func (op *exprTypeTest) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, 1)
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.op = children[0]
+	return &cp, nil
+}
+
+// NewExprTypeTest creates a type test Expression. An error is returned if the type operator is unknown or if, for
+// as, the Expression can't return a value of the type (the result type of the Expression must be VTAny or the
+// type itself unless the type is VTAny).
+func NewExprTypeTest(to TypeOperator, op Expression, ts TypeSignature, line, col int) (Expression, error) {
+	var rt TypeSignature
+	switch to {
+	case TOIs:
+		rt = NewScalarTypeSignature(VTBoolean)
+	case TOAs:
+		if !ts.IsValueType(VTAny) && !op.ResultType().IsValueType(VTAny) && !op.ExpectedResultType(ts) {
+			return nil, fmt.Errorf("a value of type %s can't be used as %s", op.ResultType().Text(), ts.Text())
+		}
+		rt = ts
+	default:
+		return nil, fmt.Errorf("unknown type operator %v", to)
+	}
+	return &exprTypeTest{
+		baseExpression: newBaseExpression(rt, line, col),
+		to:             to,
+		op:             op,
+		ts:             ts,
+	}, nil
+}
+
+func NewExprTypeTestMust(to TypeOperator, op Expression, ts TypeSignature, line, col int) Expression {
+	expr, err := NewExprTypeTest(to, op, ts, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating type test expression: %v", err))
+	}
+	return expr
+}
@@ -0,0 +1,97 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func TestExprTypeTest_String(t *testing.T) {
+	l, c := 1, 2
+	ref := NewExprTypedHeapReference("x", "x", NewScalarTypeSignature(VTAny), l, c)
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"is", NewExprTypeTestMust(TOIs, ref, NewScalarTypeSignature(VTString), l, c), `(x is string)`},
+		{"as", NewExprTypeTestMust(TOAs, ref,
+			NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTInteger)), l, c),
+			`(x as list<integer>)`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprTypeTest_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	tsAny := NewScalarTypeSignature(VTAny)
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	tsString := NewScalarTypeSignature(VTString)
+	anyOf := func(v Value) Expression {
+		return NewExprConstant(NewExprValueAny(v), l, c)
+	}
+	mixed := NewExprConstant(NewExprValueMust(NewCompositeTypeSignature(VTList, tsAny), []Value{
+		NewExprValueAny(NewExprValueString("a")), NewExprValueAny(NewExprValueInteger(1)),
+	}), l, c)
+	tests := []struct {
+		name   string
+		op     Expression
+		result Value
+		err    bool
+	}{
+		{"isTrue", NewExprTypeTestMust(TOIs, anyOf(NewExprValueString("a")), tsString, l, c), EvBooleanTrue, false},
+		{"isFalse", NewExprTypeTestMust(TOIs, anyOf(NewExprValueInteger(1)), tsString, l, c), EvBooleanFalse, false},
+		{"isNil", NewExprTypeTestMust(TOIs, NewExprConstant(NewNilExprValue(tsAny), l, c), tsString, l, c),
+			EvBooleanFalse, false},
+		{"isConcrete", NewExprTypeTestMust(TOIs, NewExprConstant(NewExprValueInteger(1), l, c), tsInteger, l, c),
+			EvBooleanTrue, false},
+		{"isListElement", NewExprTypeTestMust(TOIs, NewExprValueReference("m", 1, mixed, l, c), tsInteger, l, c),
+			EvBooleanTrue, false},
+		{"as", NewExprTypeTestMust(TOAs, anyOf(NewExprValueInteger(1)), tsInteger, l, c),
+			NewExprValueInteger(1), false},
+		{"asNil", NewExprTypeTestMust(TOAs, NewExprConstant(NewNilExprValue(tsAny), l, c), tsInteger, l, c),
+			EvNilInteger, false},
+		{"asAny", NewExprTypeTestMust(TOAs, NewExprConstant(NewExprValueInteger(1), l, c), tsAny, l, c),
+			NewExprValueAny(NewExprValueInteger(1)), false},
+		{"asWrongType", NewExprTypeTestMust(TOAs, anyOf(NewExprValueString("a")), tsInteger, l, c),
+			EvNilInteger, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.op.Evaluate(newEmptyTestRequestContext())
+			if test.err {
+				if err == nil {
+					t.Errorf("expected evaluation error")
+				} else if ErrorKindOf(err) != EKConversion {
+					t.Errorf("wrong error kind.\nactual:   %v\nexpected: %v", ErrorKindOf(err), EKConversion)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestNewExprTypeTest_Error(t *testing.T) {
+	l, c := 1, 2
+	integer := NewExprConstant(NewExprValueInteger(1), l, c)
+	_, err := NewExprTypeTest(TypeOperator("unknown"), integer, NewScalarTypeSignature(VTInteger), l, c)
+	if err == nil {
+		t.Errorf("expected error for unknown type operator")
+	}
+	_, err = NewExprTypeTest(TOAs, integer, NewScalarTypeSignature(VTString), l, c)
+	if err == nil {
+		t.Errorf("expected error for as with a non-any value of another type")
+	}
+}
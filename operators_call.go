@@ -0,0 +1,192 @@
+package goexpr
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// FunctionImpl is the go implementation of a user-defined function. The function is called with the evaluated
+// argument values. The values have the types specified by the parameters of the function.
+// The returned value must be of the result type of the function.
+type FunctionImpl func(args []Value) (Value, error)
+
+// Function holds the definition of a user-defined function.
+type Function struct {
+	// The name used to call the function
+	Name string
+	// The types of the function parameters
+	Params []TypeSignature
+	// If true the last parameter may be repeated zero or more times
+	Variadic bool
+	// The type of the function result
+	Result TypeSignature
+	// If true nil arguments are passed to the implementation. Otherwise the function is not called if an argument
+	// is nil and the result is nil (the function propagates nil).
+	AcceptNil bool
+	// The function implementation
+	Impl FunctionImpl
+}
+
+// params returns the parameter types for a call with the specified number of arguments. If the number of arguments
+// doesn't match the parameters nil and false is returned.
+func (f Function) params(args int) ([]TypeSignature, bool) {
+	if !f.Variadic {
+		return f.Params, args == len(f.Params)
+	}
+	fixed := len(f.Params) - 1
+	if args < fixed {
+		return nil, false
+	}
+	params := append([]TypeSignature(nil), f.Params[:fixed]...)
+	for len(params) < args {
+		params = append(params, f.Params[fixed])
+	}
+	return params, true
+}
+
+// FunctionRegistry holds user-defined functions that may be called from expressions (see NewExprCall()).
+// A registry may be used concurrently.
+type FunctionRegistry struct {
+	mu        sync.RWMutex
+	functions map[string]Function
+}
+
+// NewFunctionRegistry creates a new empty function registry.
+func NewFunctionRegistry() *FunctionRegistry {
+	return &FunctionRegistry{functions: make(map[string]Function)}
+}
+
+// Register registers a function in the registry. An error is returned if the function definition is invalid or if
+// a function with the same name is already registered.
+func (fr *FunctionRegistry) Register(f Function) error {
+	if f.Name == "" {
+		return fmt.Errorf("function name must be specified")
+	}
+	if f.Impl == nil {
+		return fmt.Errorf("function %s has no implementation", f.Name)
+	}
+	if f.Result.Empty() {
+		return fmt.Errorf("function %s has no result type", f.Name)
+	}
+	if f.Variadic && len(f.Params) == 0 {
+		return fmt.Errorf("variadic function %s must have at least one parameter", f.Name)
+	}
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if _, ok := fr.functions[f.Name]; ok {
+		return fmt.Errorf("function %s already registered", f.Name)
+	}
+	fr.functions[f.Name] = f
+	return nil
+}
+
+// Lookup returns the function registered with the specified name. If no such function exist false is returned.
+func (fr *FunctionRegistry) Lookup(name string) (Function, bool) {
+	fr.mu.RLock()
+	defer fr.mu.RUnlock()
+	f, ok := fr.functions[name]
+	return f, ok
+}
+
+// Names returns the sorted names of all registered functions.
+func (fr *FunctionRegistry) Names() []string {
+	fr.mu.RLock()
+	defer fr.mu.RUnlock()
+	names := make([]string, 0, len(fr.functions))
+	for name := range fr.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// exprCall calls a user-defined function with the results of the argument expressions.
+// The result of the Expression is the value returned by the function.
+// All arguments are evaluated. If one of them is nil and the function doesn't accept nil then the function is not
+// called and nil is returned. That is the call Expression propagates nil.
+// If the function returns an error or a value of the wrong type an error is returned.
+type exprCall struct {
+	baseExpression
+	// The called function (resolved when the Expression is created)
+	f    Function
+	args []Expression
+}
+
+func (op *exprCall) Evaluate(recCtx RequestContext) (Value, error) {
+	args := make([]Value, 0, len(op.args))
+	for _, argOp := range op.args {
+		arg, err := argOp.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		args = append(args, arg)
+	}
+	if !op.f.AcceptNil {
+		for _, arg := range args {
+			if arg.Nil() {
+				return op.nilResult(), nil
+			}
+		}
+	}
+	res, err := op.f.Impl(args)
+	if err != nil {
+		return op.nilResult(), fmt.Errorf("error calling function %s (%d:%d): %w", op.f.Name, op.Line(), op.Col(), err)
+	}
+	if res.Nil() {
+		return op.nilResult(), nil
+	}
+	if !res.Type.Equal(op.ResultType()) {
+		return op.nilResult(), fmt.Errorf("function %s (%d:%d) returned a value of type %v (expected %v)",
+			op.f.Name, op.Line(), op.Col(), res.Type, op.ResultType())
+	}
+	return res, nil
+}
+
+func (op *exprCall) String() string {
+	return functionString(op.f.Name, op.args)
+}
+
+func (op *exprCall) Children() []Expression {
+	return append([]Expression(nil), op.args...)
+}
+
+func (op *exprCall) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.args))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.args = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
+// NewExprCall creates an Expression calling the function with the specified name in the function registry.
+// An error is returned if the function is not registered or if the arguments don't match the function parameters.
+func NewExprCall(registry *FunctionRegistry, name string, args []Expression, line, col int) (Expression, error) {
+	f, ok := registry.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	params, ok := f.params(len(args))
+	if !ok {
+		return nil, fmt.Errorf("wrong number of arguments (%d) calling function %s", len(args), name)
+	}
+	err := checkArguments(name, args, params)
+	if err != nil {
+		return nil, err
+	}
+	return &exprCall{
+		baseExpression: newBaseExpression(f.Result, line, col),
+		f:              f,
+		args:           args,
+	}, nil
+}
+
+func NewExprCallMust(registry *FunctionRegistry, name string, args []Expression, line, col int) Expression {
+	expr, err := NewExprCall(registry, name, args, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating call expression: %v", err))
+	}
+	return expr
+}
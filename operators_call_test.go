@@ -0,0 +1,187 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newTestFunctionRegistry() *FunctionRegistry {
+	tsString := NewScalarTypeSignature(VTString)
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	registry := NewFunctionRegistry()
+	functions := []Function{
+		{Name: "repeat", Params: []TypeSignature{tsString, tsInteger}, Result: tsString,
+			Impl: func(args []Value) (Value, error) {
+				return NewExprValueString(strings.Repeat(args[0].Value.(string), args[1].Value.(int))), nil
+			}},
+		{Name: "sum", Params: []TypeSignature{tsInteger}, Variadic: true, Result: tsInteger,
+			Impl: func(args []Value) (Value, error) {
+				sum := 0
+				for _, arg := range args {
+					sum += arg.Value.(int)
+				}
+				return NewExprValueInteger(sum), nil
+			}},
+		{Name: "isNil", Params: []TypeSignature{tsString}, AcceptNil: true, Result: NewScalarTypeSignature(VTBoolean),
+			Impl: func(args []Value) (Value, error) {
+				return NewExprValueBoolean(args[0].Nil()), nil
+			}},
+		{Name: "fail", Params: []TypeSignature{}, Result: tsString,
+			Impl: func(args []Value) (Value, error) {
+				return EvNilString, fmt.Errorf("failed")
+			}},
+		{Name: "wrongType", Params: []TypeSignature{}, Result: tsString,
+			Impl: func(args []Value) (Value, error) {
+				return NewExprValueInteger(1), nil
+			}},
+	}
+	for _, f := range functions {
+		err := registry.Register(f)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return registry
+}
+
+func TestFunctionRegistry_Register(t *testing.T) {
+	registry := newTestFunctionRegistry()
+	impl := func(args []Value) (Value, error) { return EvNilString, nil }
+	tests := []struct {
+		name string
+		f    Function
+	}{
+		{"noName", Function{Result: TsDefault, Impl: impl}},
+		{"noImpl", Function{Name: "noImpl", Result: TsDefault}},
+		{"noResult", Function{Name: "noResult", Impl: impl}},
+		{"variadicNoParams", Function{Name: "variadic", Variadic: true, Result: TsDefault, Impl: impl}},
+		{"duplicate", Function{Name: "repeat", Result: TsDefault, Impl: impl}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := registry.Register(test.f)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+	names := fmt.Sprint(registry.Names())
+	if names != "[fail isNil repeat sum wrongType]" {
+		t.Errorf("wrong registered function names: %s", names)
+	}
+}
+
+func TestExprCall_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	registry := newTestFunctionRegistry()
+	reqCtx := newTestRequestContext(map[string]string{"count": "3"})
+	tests := []struct {
+		name   string
+		fn     string
+		args   []Expression
+		str    string
+		result Value
+	}{
+		{"repeat", "repeat", []Expression{
+			NewExprConstant(NewExprValueString("ab"), l, c),
+			NewExprHeapReference("count", "count", l, c)},
+			`(repeat "ab" count)`, NewExprValueString("ababab")},
+		{"repeatNil", "repeat", []Expression{
+			NewExprConstant(EvNilString, l, c),
+			NewExprConstant(NewExprValueInteger(2), l, c)},
+			`(repeat <<nil>> 2)`, EvNilString},
+		{"variadic", "sum", []Expression{
+			NewExprConstant(NewExprValueInteger(1), l, c),
+			NewExprConstant(NewExprValueInteger(2), l, c),
+			NewExprConstant(NewExprValueInteger(3), l, c)},
+			`(sum 1 2 3)`, NewExprValueInteger(6)},
+		{"variadicNoArgs", "sum", []Expression{},
+			`(sum)`, NewExprValueInteger(0)},
+		{"acceptNil", "isNil", []Expression{NewExprHeapReference("missing", "missing", l, c)},
+			`(isNil missing)`, EvBooleanTrue},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, err := NewExprCall(registry, test.fn, test.args, l, c)
+			if err != nil {
+				t.Errorf("unexpected error creating expression: %v", err)
+				return
+			}
+			if op.String() != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", op.String(), test.str)
+			}
+			res, err := op.Evaluate(reqCtx)
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestExprCall_EvaluateError(t *testing.T) {
+	l, c := 1, 2
+	registry := newTestFunctionRegistry()
+	for _, name := range []string{"fail", "wrongType"} {
+		t.Run(name, func(t *testing.T) {
+			op := NewExprCallMust(registry, name, []Expression{}, l, c)
+			_, err := op.Evaluate(newEmptyTestRequestContext())
+			if err == nil {
+				t.Errorf("expected evaluation error")
+			}
+		})
+	}
+}
+
+func TestExprCall_EvaluateAllArguments(t *testing.T) {
+	l, c := 1, 2
+	registry := newTestFunctionRegistry()
+	// All arguments are evaluated (including assignments) even if an earlier argument is nil
+	op := NewExprCallMust(registry, "repeat", []Expression{
+		NewExprConstant(EvNilString, l, c),
+		NewExprAssign("n", "n", NewExprConstant(NewExprValueInteger(2), l, c), nil, RSHeap, l, c),
+	}, l, c)
+	values := make(map[string]string)
+	res, err := op.Evaluate(newTestRequestContext(values))
+	if err != nil {
+		t.Errorf("unexprected evaluation error: %v", err)
+		return
+	}
+	if !res.Equal(EvNilString) {
+		t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, EvNilString)
+	}
+	if values["n"] != "2" {
+		t.Errorf("expected the second argument to be evaluated (n = %q)", values["n"])
+	}
+}
+
+func TestNewExprCall_Error(t *testing.T) {
+	l, c := 1, 2
+	registry := newTestFunctionRegistry()
+	tests := []struct {
+		name string
+		fn   string
+		args []Expression
+	}{
+		{"unknown", "unknown", []Expression{}},
+		{"argumentCount", "repeat", []Expression{NewExprConstant(EvStringEmpty, l, c)}},
+		{"argumentType", "repeat", []Expression{
+			NewExprConstant(EvStringEmpty, l, c),
+			NewExprConstant(EvStringEmpty, l, c)}},
+		{"variadicArgumentType", "sum", []Expression{
+			NewExprConstant(NewExprValueInteger(1), l, c),
+			NewExprConstant(EvStringEmpty, l, c)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprCall(registry, test.fn, test.args, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
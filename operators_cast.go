@@ -0,0 +1,149 @@
+package goexpr
+
+import (
+	"fmt"
+)
+
+// Cast function type
+type CastFunction string
+
+const (
+	CFToBool    CastFunction = "toBool"
+	CFToDecimal CastFunction = "toDecimal"
+	CFToInt     CastFunction = "toInt"
+	CFToList    CastFunction = "toList"
+	CFToRegexp  CastFunction = "toRegexp"
+	CFToString  CastFunction = "toString"
+)
+
+// exprCast converts the result of an Expression to another value type.
+// toString: a string is returned as is and a regexp is returned as its expression. Other values are returned as
+// their string representation (see Value.String()).
+// toInt: a string is parsed as an integer (see NewExprValueFromString()). A boolean is converted to 1 (true) or
+// 0 (false).
+// toDecimal: a string is parsed as a decimal (see NewExprValueFromString()). An integer is converted to a decimal
+// with scale 0.
+// toBool: a string is parsed as a boolean (see NewExprValueFromString()). An integer is converted to true if it
+// is non-zero.
+// toRegexp: a string is compiled to a regexp.
+// toList: a scalar value is converted to a list holding only the value.
+// Converting a value to its own type returns the value as is.
+// If a value can't be converted an evaluation error of kind EKConversion is returned.
+// If the value is nil then nil is returned. That is the cast Expression propagates nil.
+type exprCast struct {
+	baseExpression
+	cf CastFunction
+	op Expression
+}
+
+func (op *exprCast) Evaluate(recCtx RequestContext) (Value, error) {
+	value, err := op.op.Evaluate(recCtx)
+	if err != nil {
+		return op.nilResult(), err
+	}
+	// Cast functions propagates nil
+	if value.Nil() {
+		return op.nilResult(), nil
+	}
+	if op.cf == CFToList {
+		return NewExprValueList(value.Type, []Value{value}), nil
+	}
+	if value.Type.Equal(op.ResultType()) {
+		return value, nil
+	}
+
+	switch value.Type.BaseType {
+	case VTString, VTRegexp:
+		res, err := NewExprValueFromString(op.ResultType(), value.Value.(string))
+		if err != nil {
+			return op.nilResult(), NewEvaluationError(EKConversion,
+				fmt.Errorf("can't convert %v to %v: %w", value, op.ResultType().BaseType, err), op.Line(), op.Col())
+		}
+		return res, nil
+	}
+	switch op.cf {
+	case CFToBool:
+		return NewExprValueBoolean(value.Value.(int) != 0), nil
+	case CFToDecimal:
+		return NewExprValueDecimal(NewDecimalFromInt(value.Value.(int))), nil
+	case CFToInt:
+		if value.Value.(bool) {
+			return NewExprValueInteger(1), nil
+		}
+		return NewExprValueInteger(0), nil
+	case CFToString:
+		return NewExprValueString(value.String()), nil
+	default:
+		panic(fmt.Sprintf("can't %s a value of type %v", op.cf, value.Type))
+	}
+}
+
+func (op *exprCast) String() string {
+	return functionString(string(op.cf), []Expression{op.op})
+}
+
+func (op *exprCast) Children() []Expression {
+	return []Expression{op.op}
+}
+
+func (op *exprCast) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, 1)
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.op = children[0]
+	return &cp, nil
+}
+
+// castableFrom returns true if the type signature has one of the value types
+func castableFrom(ts TypeSignature, from []ValueType) bool {
+	for _, vt := range from {
+		if ts.IsValueType(vt) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewExprCast creates a cast Expression. An error is returned if the cast function is unknown or if the result
+// type of the Expression can't be converted by the cast function.
+func NewExprCast(cf CastFunction, op Expression, line, col int) (Expression, error) {
+	ts := op.ResultType()
+	var rt TypeSignature
+	var from []ValueType
+	switch cf {
+	case CFToBool:
+		rt, from = NewScalarTypeSignature(VTBoolean), []ValueType{VTBoolean, VTInteger, VTString}
+	case CFToDecimal:
+		rt, from = NewScalarTypeSignature(VTDecimal), []ValueType{VTDecimal, VTInteger, VTString}
+	case CFToInt:
+		rt, from = NewScalarTypeSignature(VTInteger), []ValueType{VTBoolean, VTInteger, VTString}
+	case CFToList:
+		rt, from = NewCompositeTypeSignature(VTList, ts), []ValueType{VTBoolean, VTDecimal, VTInteger, VTRegexp,
+			VTString}
+	case CFToRegexp:
+		rt, from = NewScalarTypeSignature(VTRegexp), []ValueType{VTRegexp, VTString}
+	case CFToString:
+		rt, from = NewScalarTypeSignature(VTString), []ValueType{VTBoolean, VTDecimal, VTInteger, VTList,
+			VTMap, VTRegexp, VTString}
+	default:
+		return nil, fmt.Errorf("unknown cast function %v", cf)
+	}
+	if !castableFrom(ts, from) {
+		return nil, fmt.Errorf("%s can't convert a value of type %v", cf, ts)
+	}
+	return &exprCast{
+		baseExpression: newBaseExpression(rt, line, col),
+		cf:             cf,
+		op:             op,
+	}, nil
+}
+
+func NewExprCastMust(cf CastFunction, op Expression, line, col int) Expression {
+	expr, err := NewExprCast(cf, op, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating cast expression: %v", err))
+	}
+	return expr
+}
@@ -0,0 +1,127 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func TestExprCast_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	constant := func(v Value) Expression {
+		return NewExprConstant(v, l, c)
+	}
+	str := func(s string) Expression {
+		return constant(NewExprValueString(s))
+	}
+	integer := func(i int) Expression {
+		return constant(NewExprValueInteger(i))
+	}
+	tests := []struct {
+		name   string
+		cf     CastFunction
+		op     Expression
+		result Value
+	}{
+		{"stringToInt", CFToInt, str("42"), NewExprValueInteger(42)},
+		{"referenceToInt", CFToInt, NewExprHeapReference("count", "count", l, c), NewExprValueInteger(5)},
+		{"booleanToInt", CFToInt, constant(EvBooleanTrue), NewExprValueInteger(1)},
+		{"intToInt", CFToInt, integer(3), NewExprValueInteger(3)},
+		{"stringToBool", CFToBool, str("true"), EvBooleanTrue},
+		{"intToBool", CFToBool, integer(0), EvBooleanFalse},
+		{"intToString", CFToString, integer(-7), NewExprValueString("-7")},
+		{"booleanToString", CFToString, constant(EvBooleanFalse), NewExprValueString("false")},
+		{"stringToString", CFToString, str("foo"), NewExprValueString("foo")},
+		{"regexpToString", CFToString, constant(NewExprValueRegexpMust("[a-z]+")), NewExprValueString("[a-z]+")},
+		{"listToString", CFToString, constant(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{
+			NewExprValueInteger(1), NewExprValueInteger(2)})), NewExprValueString("[1,2]")},
+		{"stringToDecimal", CFToDecimal, str("19.99"), NewExprValueDecimal(NewDecimal(1999, 2))},
+		{"intToDecimal", CFToDecimal, integer(-3), NewExprValueDecimal(NewDecimalFromInt(-3))},
+		{"decimalToString", CFToString, constant(NewExprValueDecimal(NewDecimal(50, 2))), NewExprValueString("0.50")},
+		{"stringToRegexp", CFToRegexp, str("[a-z]+"), NewExprValueRegexpMust("[a-z]+")},
+		{"intToList", CFToList, integer(1), NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{
+			NewExprValueInteger(1)})},
+		// nil ---------------------------------------
+		{"nilToInt", CFToInt, constant(EvNilString), EvNilInteger},
+		{"nilReferenceToBool", CFToBool, NewExprHeapReference("missing", "missing", l, c), EvNilBoolean},
+		{"nilToList", CFToList, constant(EvNilString),
+			NewNilExprValue(NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString)))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, err := NewExprCast(test.cf, test.op, l, c)
+			if err != nil {
+				t.Errorf("unexpected error creating expression: %v", err)
+				return
+			}
+			if !op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", op.ResultType(), test.result.Type)
+			}
+			res, err := op.Evaluate(newTestRequestContext(map[string]string{"count": "5"}))
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestExprCast_EvaluateError(t *testing.T) {
+	l, c := 3, 4
+	tests := []struct {
+		name string
+		cf   CastFunction
+		str  string
+		msg  string
+	}{
+		{"toInt", CFToInt, "foo",
+			`conversion error (3:4): can't convert "foo" to integer: strconv.Atoi: parsing "foo": invalid syntax`},
+		{"toBool", CFToBool, "yes",
+			`conversion error (3:4): can't convert "yes" to boolean: strconv.ParseBool: parsing "yes": invalid syntax`},
+		{"toDecimal", CFToDecimal, "1,5",
+			`conversion error (3:4): can't convert "1,5" to decimal: invalid decimal "1,5"`},
+		{"toRegexp", CFToRegexp, "(",
+			"conversion error (3:4): can't convert \"(\" to regexp: error pre-compiling regexp (: " +
+				"error parsing regexp: missing closing ): `(`"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op := NewExprCastMust(test.cf, NewExprConstant(NewExprValueString(test.str), l, c), l, c)
+			_, err := op.Evaluate(newEmptyTestRequestContext())
+			if err == nil {
+				t.Errorf("expected evaluation error")
+				return
+			}
+			if ErrorKindOf(err) != EKConversion {
+				t.Errorf("wrong error kind.\nactual:   %v\nexpected: %v", ErrorKindOf(err), EKConversion)
+			}
+			if err.Error() != test.msg {
+				t.Errorf("wrong error message.\nactual:   %v\nexpected: %v", err, test.msg)
+			}
+		})
+	}
+}
+
+func TestNewExprCast_Error(t *testing.T) {
+	l, c := 1, 2
+	list := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{}), l, c)
+	tests := []struct {
+		name string
+		cf   CastFunction
+		op   Expression
+	}{
+		{"unknown", CastFunction("unknown"), list},
+		{"listToInt", CFToInt, list},
+		{"listToList", CFToList, list},
+		{"integerToRegexp", CFToRegexp, NewExprConstant(NewExprValueInteger(1), l, c)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprCast(test.cf, test.op, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package goexpr
+
+import (
+	"fmt"
+)
+
+// Decimal operation type
+type DecimalOperation string
+
+const (
+	DOAdd   DecimalOperation = "add"
+	DODiv   DecimalOperation = "div"
+	DOMul   DecimalOperation = "mul"
+	DORound DecimalOperation = "round"
+	DOSub   DecimalOperation = "sub"
+)
+
+// exprDecimal applies an arithmetic operation to decimals (see Decimal).
+// add <decimal1> <decimal2> => decimal1 + decimal2 (exact)
+// sub <decimal1> <decimal2> => decimal1 - decimal2 (exact)
+// mul <decimal1> <decimal2> => decimal1 * decimal2 (exact)
+// div <decimal1> <decimal2> => decimal1 / decimal2 with the scale of the operation rounded using the rounding
+// mode of the operation. An evaluation error is returned if decimal2 is zero.
+// round <decimal> => the decimal with the scale of the operation rounded using the rounding mode of the operation.
+// If one of the arguments evaluates to nil then nil is returned. That is the decimal Expression propagates nil.
+type exprDecimal struct {
+	baseExpression
+	do   DecimalOperation
+	args []Expression
+	// The scale and rounding mode of the result (div and round only)
+	scale int
+	rm    RoundingMode
+}
+
+func (op *exprDecimal) Evaluate(recCtx RequestContext) (Value, error) {
+	args := make([]Decimal, 0, len(op.args))
+	for _, argOp := range op.args {
+		arg, err := argOp.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		// Decimal operations propagates nil
+		if arg.Nil() {
+			return op.nilResult(), nil
+		}
+		args = append(args, arg.Value.(Decimal))
+	}
+
+	switch op.do {
+	case DOAdd:
+		return NewExprValueDecimal(args[0].Add(args[1])), nil
+	case DODiv:
+		quo, err := args[0].Quo(args[1], op.scale, op.rm)
+		if err != nil {
+			return op.nilResult(), NewEvaluationError(EKOther, err, op.Line(), op.Col())
+		}
+		return NewExprValueDecimal(quo), nil
+	case DOMul:
+		return NewExprValueDecimal(args[0].Mul(args[1])), nil
+	case DORound:
+		return NewExprValueDecimal(args[0].Round(op.scale, op.rm)), nil
+	case DOSub:
+		return NewExprValueDecimal(args[0].Sub(args[1])), nil
+	default:
+		panic(fmt.Sprintf("unknown decimal operation %v", op.do))
+	}
+}
+
+// name returns the name of the decimal operation including the scale and rounding mode (e.g. "round 2 halfUp")
+func (op *exprDecimal) name() string {
+	if op.do == DODiv || op.do == DORound {
+		return fmt.Sprintf("%s %d %s", op.do, op.scale, op.rm)
+	}
+	return string(op.do)
+}
+
+func (op *exprDecimal) String() string {
+	return functionString(op.name(), op.args)
+}
+
+func (op *exprDecimal) Children() []Expression {
+	return append([]Expression(nil), op.args...)
+}
+
+func (op *exprDecimal) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.args))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.args = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
+// NewExprDecimal creates an exact decimal operation Expression (add, sub or mul). For div and round see
+// NewExprDecimalRounded(). An error is returned if the decimal operation is unknown or if the arguments are not
+// two decimals.
+func NewExprDecimal(do DecimalOperation, args []Expression, line, col int) (Expression, error) {
+	switch do {
+	case DOAdd, DOMul, DOSub:
+	case DODiv, DORound:
+		return nil, fmt.Errorf("use NewExprDecimalRounded to create a %s expression", do)
+	default:
+		return nil, fmt.Errorf("unknown decimal operation %v", do)
+	}
+	return newExprDecimal(do, args, 0, "", line, col)
+}
+
+func NewExprDecimalMust(do DecimalOperation, args []Expression, line, col int) Expression {
+	expr, err := NewExprDecimal(do, args, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating decimal expression: %v", err))
+	}
+	return expr
+}
+
+// NewExprDecimalRounded creates a decimal operation Expression (div or round) with a result of the specified scale
+// rounded using the rounding mode. An error is returned if the decimal operation isn't div or round, if the scale
+// is negative, if the rounding mode is unknown or if the arguments are not decimals (two for div and one for
+// round).
+func NewExprDecimalRounded(do DecimalOperation, args []Expression, scale int, rm RoundingMode, line,
+	col int) (Expression, error) {
+	if do != DODiv && do != DORound {
+		return nil, fmt.Errorf("decimal operation %v has no scale or rounding mode", do)
+	}
+	if scale < 0 {
+		return nil, fmt.Errorf("negative scale %d", scale)
+	}
+	if !ValidRoundingMode(rm) {
+		return nil, fmt.Errorf("unknown rounding mode %v", rm)
+	}
+	return newExprDecimal(do, args, scale, rm, line, col)
+}
+
+func NewExprDecimalRoundedMust(do DecimalOperation, args []Expression, scale int, rm RoundingMode, line,
+	col int) Expression {
+	expr, err := NewExprDecimalRounded(do, args, scale, rm, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating decimal expression: %v", err))
+	}
+	return expr
+}
+
+func newExprDecimal(do DecimalOperation, args []Expression, scale int, rm RoundingMode, line,
+	col int) (Expression, error) {
+	tsDecimal := NewScalarTypeSignature(VTDecimal)
+	params := []TypeSignature{tsDecimal, tsDecimal}
+	if do == DORound {
+		params = params[:1]
+	}
+	err := checkArguments(string(do), args, params)
+	if err != nil {
+		return nil, err
+	}
+	return &exprDecimal{
+		baseExpression: newBaseExpression(tsDecimal, line, col),
+		do:             do,
+		args:           args,
+		scale:          scale,
+		rm:             rm,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func TestExprDecimal_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	dec := func(s string) Expression {
+		return NewExprConstant(NewExprValueDecimal(ParseDecimalMust(s)), l, c)
+	}
+	nilDecimal := NewExprConstant(NewNilExprValue(NewScalarTypeSignature(VTDecimal)), l, c)
+	tests := []struct {
+		name   string
+		op     Expression
+		result string
+	}{
+		{"add", NewExprDecimalMust(DOAdd, []Expression{dec("0.10"), dec("0.2")}, l, c), "0.30"},
+		{"sub", NewExprDecimalMust(DOSub, []Expression{dec("10"), dec("0.01")}, l, c), "9.99"},
+		{"mul", NewExprDecimalMust(DOMul, []Expression{dec("19.99"), dec("0.25")}, l, c), "4.9975"},
+		{"div", NewExprDecimalRoundedMust(DODiv, []Expression{dec("100"), dec("3")}, 2, RMHalfEven, l, c), "33.33"},
+		{"round", NewExprDecimalRoundedMust(DORound, []Expression{dec("4.9975")}, 2, RMHalfUp, l, c), "5.00"},
+		{"nested", NewExprDecimalRoundedMust(DORound, []Expression{
+			NewExprDecimalMust(DOMul, []Expression{dec("19.99"), dec("0.25")}, l, c)}, 2, RMDown, l, c), "4.99"},
+		// nil ---------------------------------------
+		{"addNil", NewExprDecimalMust(DOAdd, []Expression{dec("1"), nilDecimal}, l, c), "<<nil>>"},
+		{"divNil", NewExprDecimalRoundedMust(DODiv, []Expression{nilDecimal, dec("0")}, 2, RMHalfUp, l, c),
+			"<<nil>>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.op.Evaluate(newEmptyTestRequestContext())
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Type.IsValueType(VTDecimal) || res.String() != test.result {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestExprDecimal_EvaluateError(t *testing.T) {
+	l, c := 3, 4
+	dec := func(s string) Expression {
+		return NewExprConstant(NewExprValueDecimal(ParseDecimalMust(s)), l, c)
+	}
+	op := NewExprDecimalRoundedMust(DODiv, []Expression{dec("1"), dec("0.0")}, 2, RMHalfUp, l, c)
+	_, err := op.Evaluate(newEmptyTestRequestContext())
+	if err == nil {
+		t.Errorf("expected evaluation error")
+		return
+	}
+	if ErrorKindOf(err) != EKOther {
+		t.Errorf("wrong error kind.\nactual:   %v\nexpected: %v", ErrorKindOf(err), EKOther)
+	}
+}
+
+func TestExprDecimal_String(t *testing.T) {
+	l, c := 1, 2
+	price := NewExprTypedHeapReference("price", "price", NewScalarTypeSignature(VTDecimal), l, c)
+	qty := NewExprCastMust(CFToDecimal, NewExprHeapReference("qty", "qty", l, c), l, c)
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"mul", NewExprDecimalMust(DOMul, []Expression{price, qty}, l, c), "(mul price (toDecimal qty))"},
+		{"round", NewExprDecimalRoundedMust(DORound, []Expression{price}, 2, RMHalfEven, l, c),
+			"(round 2 halfEven price)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestNewExprDecimal_Error(t *testing.T) {
+	l, c := 1, 2
+	dec := NewExprConstant(NewExprValueDecimal(NewDecimalFromInt(1)), l, c)
+	integer := NewExprConstant(NewExprValueInteger(1), l, c)
+	tests := []struct {
+		name string
+		f    func() (Expression, error)
+	}{
+		{"unknown", func() (Expression, error) {
+			return NewExprDecimal("pow", []Expression{dec, dec}, l, c)
+		}},
+		{"divWithoutScale", func() (Expression, error) {
+			return NewExprDecimal(DODiv, []Expression{dec, dec}, l, c)
+		}},
+		{"integerArgument", func() (Expression, error) {
+			return NewExprDecimal(DOAdd, []Expression{dec, integer}, l, c)
+		}},
+		{"wrongArgumentCount", func() (Expression, error) {
+			return NewExprDecimal(DOMul, []Expression{dec}, l, c)
+		}},
+		{"addWithScale", func() (Expression, error) {
+			return NewExprDecimalRounded(DOAdd, []Expression{dec, dec}, 2, RMHalfUp, l, c)
+		}},
+		{"negativeScale", func() (Expression, error) {
+			return NewExprDecimalRounded(DORound, []Expression{dec}, -1, RMHalfUp, l, c)
+		}},
+		{"unknownRoundingMode", func() (Expression, error) {
+			return NewExprDecimalRounded(DORound, []Expression{dec}, 2, "nearest", l, c)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.f()
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lambda Expression type
+type LambdaType string
+
+const (
+	LATAll    LambdaType = "all"
+	LATAny    LambdaType = "any"
+	LATCount  LambdaType = "count"
+	LATFilter LambdaType = "filter"
+	LATMap    LambdaType = "map"
+	LATReduce LambdaType = "reduce"
+)
+
+// exprLambda applies a body Expression to each value in a list (the result of the list Expression).
+// As for exprFor the reference specified by the heap key is set to the current list value before the body
+// Expression is evaluated. The result of the Expression is specific to the lambda type.
+// All: true if the body (must be a boolean) evaluates to true for all list values. If the body evaluates to false
+// for any value the result is false. Otherwise, if the body evaluates to nil for any value, the result is nil.
+// The result for an empty list is true.
+// Any: true if the body (must be a boolean) evaluates to true for any list value. Otherwise, if the body evaluates
+// to nil for any value, the result is nil. Otherwise the result is false (also for an empty list).
+// Count: the number of list values for which the body (must be a boolean) evaluates to true.
+// Filter: a list (of the same type as the list) with the values for which the body (must be a boolean) evaluates
+// to true.
+// Map: a list with the results of the body for each list value. The unit type of the list is the result type of
+// the body.
+// Reduce: the accumulator reference (specified by the accumulator key) is set to the result of the init Expression.
+// For each list value the accumulator is then set to the result of the body. The result is the final accumulator
+// value. The body must have the same result type as the init Expression.
+// Note that all and any stop evaluating the body as soon as the result is known.
+// If the list is nil then nil is returned. That is the lambda Expression propagates nil.
+type exprLambda struct {
+	baseExpression
+	lt LambdaType
+	// The list of values to apply the body to
+	opList Expression
+	// The initial accumulator value (reduce only)
+	opInit Expression
+	// The Expression applied to each value in the list
+	opBody Expression
+	// The reference heap key where to store the current value of the list
+	key string
+	// The reference heap key where to store the accumulator (reduce only)
+	accKey string
+}
+
+func (op *exprLambda) Evaluate(recCtx RequestContext) (Value, error) {
+	list, err := op.opList.Evaluate(recCtx)
+	if err != nil {
+		return op.nilResult(), err
+	}
+	if list.Nil() {
+		return op.nilResult(), nil
+	}
+	var acc Value
+	if op.lt == LATReduce {
+		acc, err = op.opInit.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+	}
+
+	values := list.Value.([]Value)
+	results := make([]Value, 0, len(values))
+	count := 0
+	sawNil := false
+	for _, value := range values {
+		err := recCtx.Assign(op.key, value)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		if op.lt == LATReduce {
+			err := recCtx.Assign(op.accKey, acc)
+			if err != nil {
+				return op.nilResult(), err
+			}
+		}
+		res, err := op.opBody.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		switch op.lt {
+		case LATAll:
+			if res.Nil() {
+				sawNil = true
+			} else if !res.Value.(bool) {
+				return EvBooleanFalse, nil
+			}
+		case LATAny:
+			if res.Nil() {
+				sawNil = true
+			} else if res.Value.(bool) {
+				return EvBooleanTrue, nil
+			}
+		case LATCount:
+			if !res.Nil() && res.Value.(bool) {
+				count++
+			}
+		case LATFilter:
+			if !res.Nil() && res.Value.(bool) {
+				results = append(results, value)
+			}
+		case LATMap:
+			results = append(results, res)
+		case LATReduce:
+			acc = res
+		default:
+			panic(fmt.Sprintf("unknown lambda type %v", op.lt))
+		}
+	}
+
+	switch op.lt {
+	case LATAll:
+		if sawNil {
+			return op.nilResult(), nil
+		}
+		return EvBooleanTrue, nil
+	case LATAny:
+		if sawNil {
+			return op.nilResult(), nil
+		}
+		return EvBooleanFalse, nil
+	case LATCount:
+		return NewExprValueInteger(count), nil
+	case LATFilter, LATMap:
+		return NewExprValueList(*op.ResultType().UnitType, results), nil
+	case LATReduce:
+		return acc, nil
+	default:
+		panic(fmt.Sprintf("unknown lambda type %v", op.lt))
+	}
+}
+
+func (op *exprLambda) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString(string(op.lt))
+	sb.WriteString(" ")
+	sb.WriteString(op.key)
+	sb.WriteString(" in ")
+	sb.WriteString(op.opList.String())
+	if op.lt == LATReduce {
+		sb.WriteString(" with ")
+		sb.WriteString(op.accKey)
+		sb.WriteString(" = ")
+		sb.WriteString(op.opInit.String())
+	}
+	sb.WriteString(" do ")
+	sb.WriteString(op.opBody.String())
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func (op *exprLambda) Children() []Expression {
+	if op.opInit != nil {
+		return []Expression{op.opList, op.opInit, op.opBody}
+	}
+	return []Expression{op.opList, op.opBody}
+}
+
+func (op *exprLambda) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.Children()))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.opList, cp.opBody = children[0], children[len(children)-1]
+	if op.opInit != nil {
+		cp.opInit = children[1]
+	}
+	return &cp, nil
+}
+
+// NewExprLambda creates a lambda Expression of the specified type (except reduce, see NewExprReduce()).
+// An error is returned if the list Expression doesn't return a list or if the body has the wrong result type.
+func NewExprLambda(lt LambdaType, opList, opBody Expression, key string, line, col int) (Expression, error) {
+	if !opList.ResultType().IsValueType(VTList) {
+		return nil, fmt.Errorf("%s expects a list (got %v)", lt, opList.ResultType())
+	}
+	tsBoolean := NewScalarTypeSignature(VTBoolean)
+	var rt TypeSignature
+	switch lt {
+	case LATAll, LATAny:
+		rt = tsBoolean
+	case LATCount:
+		rt = NewScalarTypeSignature(VTInteger)
+	case LATFilter:
+		rt = opList.ResultType()
+	case LATMap:
+		rt = NewCompositeTypeSignature(VTList, opBody.ResultType())
+	case LATReduce:
+		return nil, fmt.Errorf("use NewExprReduce to create a reduce expression")
+	default:
+		return nil, fmt.Errorf("unknown lambda type %v", lt)
+	}
+	if lt != LATMap && !opBody.ExpectedResultType(tsBoolean) {
+		return nil, fmt.Errorf("%s expects a boolean body (got %v)", lt, opBody.ResultType())
+	}
+	return &exprLambda{
+		baseExpression: newBaseExpression(rt, line, col),
+		lt:             lt,
+		opList:         opList,
+		opBody:         opBody,
+		key:            key,
+	}, nil
+}
+
+func NewExprLambdaMust(lt LambdaType, opList, opBody Expression, key string, line, col int) Expression {
+	expr, err := NewExprLambda(lt, opList, opBody, key, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating lambda expression: %v", err))
+	}
+	return expr
+}
+
+// NewExprReduce creates a reduce lambda Expression. An error is returned if the list Expression doesn't return
+// a list or if the body doesn't have the same result type as the init Expression.
+func NewExprReduce(opList, opInit, opBody Expression, key, accKey string, line, col int) (Expression, error) {
+	if !opList.ResultType().IsValueType(VTList) {
+		return nil, fmt.Errorf("%s expects a list (got %v)", LATReduce, opList.ResultType())
+	}
+	if !opBody.ExpectedResultType(opInit.ResultType()) {
+		return nil, fmt.Errorf("%s expects a body of type %v (got %v)", LATReduce, opInit.ResultType(),
+			opBody.ResultType())
+	}
+	return &exprLambda{
+		baseExpression: newBaseExpression(opInit.ResultType(), line, col),
+		lt:             LATReduce,
+		opList:         opList,
+		opInit:         opInit,
+		opBody:         opBody,
+		key:            key,
+		accKey:         accKey,
+	}, nil
+}
+
+func NewExprReduceMust(opList, opInit, opBody Expression, key, accKey string, line, col int) Expression {
+	expr, err := NewExprReduce(opList, opInit, opBody, key, accKey, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating reduce expression: %v", err))
+	}
+	return expr
+}
@@ -0,0 +1,191 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func TestExprLambda_String(t *testing.T) {
+	l, c := 1, 2
+	list := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+		NewExprValueString("foo"),
+		NewExprValueString("bar"),
+	}), l, c)
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"map", NewExprLambdaMust(LATMap, list, NewExprStringMust(SFUpper, []Expression{
+			NewExprHeapReference("x", "x", l, c)}, l, c), "x", l, c),
+			`(map x in ["foo","bar"] do (upper x))`},
+		{"any", NewExprLambdaMust(LATAny, list, NewExprCompareMust(CTEqual,
+			NewExprHeapReference("x", "x", l, c), NewExprConstant(NewExprValueString("foo"), l, c), l, c),
+			"x", l, c),
+			`(any x in ["foo","bar"] do (x == "foo"))`},
+		{"reduce", NewExprReduceMust(list, NewExprConstant(EvStringEmpty, l, c),
+			NewExprHeapReference("x", "x", l, c), "x", "acc", l, c),
+			`(reduce x in ["foo","bar"] with acc = "" do x)`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprLambda_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	tsString := NewScalarTypeSignature(VTString)
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	strList := func(strs ...string) Value {
+		list := make([]Value, 0, len(strs))
+		for _, s := range strs {
+			list = append(list, NewExprValueString(s))
+		}
+		return NewExprValueList(tsString, list)
+	}
+	intList := func(ints ...int) Value {
+		list := make([]Value, 0, len(ints))
+		for _, i := range ints {
+			list = append(list, NewExprValueInteger(i))
+		}
+		return NewExprValueList(tsInteger, list)
+	}
+	intRef := func(key string) Expression {
+		ref := NewExprHeapReference(key, key, l, c)
+		ref.ExpectedResultType(tsInteger)
+		return ref
+	}
+	// x == "foo" ("x" is nil if the value is "nil")
+	isFoo := NewExprCompareMust(CTEqual, NewExprHeapReference("x", "x", l, c),
+		NewExprConstant(NewExprValueString("foo"), l, c), l, c)
+	// x > 2
+	greater2 := NewExprCompareMust(CTGreater, intRef("x"), NewExprConstant(NewExprValueInteger(2), l, c), l, c)
+	// x > acc ? x : acc
+	max := NewExprIf(NewExprCompareMust(CTGreater, intRef("x"), intRef("acc"), l, c), intRef("x"), intRef("acc"),
+		l, c)
+	upper := NewExprStringMust(SFUpper, []Expression{NewExprHeapReference("x", "x", l, c)}, l, c)
+	nilStrList := NewNilExprValue(NewCompositeTypeSignature(VTList, tsString))
+
+	tests := []struct {
+		name   string
+		op     Expression
+		result Value
+	}{
+		{"all", NewExprLambdaMust(LATAll, NewExprConstant(intList(3, 4, 5), l, c), greater2, "x", l, c),
+			EvBooleanTrue},
+		{"allFalse", NewExprLambdaMust(LATAll, NewExprConstant(intList(3, 1, 5), l, c), greater2, "x", l, c),
+			EvBooleanFalse},
+		{"allEmpty", NewExprLambdaMust(LATAll, NewExprConstant(intList(), l, c), greater2, "x", l, c),
+			EvBooleanTrue},
+		{"any", NewExprLambdaMust(LATAny, NewExprConstant(strList("bar", "foo"), l, c), isFoo, "x", l, c),
+			EvBooleanTrue},
+		{"anyFalse", NewExprLambdaMust(LATAny, NewExprConstant(strList("bar", "baz"), l, c), isFoo, "x", l, c),
+			EvBooleanFalse},
+		{"anyEmpty", NewExprLambdaMust(LATAny, NewExprConstant(strList(), l, c), isFoo, "x", l, c),
+			EvBooleanFalse},
+		{"count", NewExprLambdaMust(LATCount, NewExprConstant(intList(1, 3, 2, 4), l, c), greater2, "x", l, c),
+			NewExprValueInteger(2)},
+		{"countEmpty", NewExprLambdaMust(LATCount, NewExprConstant(intList(), l, c), greater2, "x", l, c),
+			NewExprValueInteger(0)},
+		{"filter", NewExprLambdaMust(LATFilter, NewExprConstant(intList(1, 3, 2, 4), l, c), greater2, "x", l, c),
+			intList(3, 4)},
+		{"filterEmpty", NewExprLambdaMust(LATFilter, NewExprConstant(intList(), l, c), greater2, "x", l, c),
+			intList()},
+		{"map", NewExprLambdaMust(LATMap, NewExprConstant(strList("foo", "bar"), l, c), upper, "x", l, c),
+			strList("FOO", "BAR")},
+		{"mapToBoolean", NewExprLambdaMust(LATMap, NewExprConstant(intList(1, 3), l, c), greater2, "x", l, c),
+			NewExprValueList(NewScalarTypeSignature(VTBoolean), []Value{EvBooleanFalse, EvBooleanTrue})},
+		{"reduce", NewExprReduceMust(NewExprConstant(intList(3, 7, 2), l, c),
+			NewExprConstant(NewExprValueInteger(0), l, c), max, "x", "acc", l, c),
+			NewExprValueInteger(7)},
+		{"reduceEmpty", NewExprReduceMust(NewExprConstant(intList(), l, c),
+			NewExprConstant(NewExprValueInteger(-1), l, c), max, "x", "acc", l, c),
+			NewExprValueInteger(-1)},
+		// nil ---------------------------------------
+		{"mapNil", NewExprLambdaMust(LATMap, NewExprConstant(nilStrList, l, c), upper, "x", l, c),
+			nilStrList},
+		{"anyNil", NewExprLambdaMust(LATAny, NewExprConstant(nilStrList, l, c), isFoo, "x", l, c),
+			EvNilBoolean},
+		{"countNil", NewExprLambdaMust(LATCount, NewExprConstant(nilStrList, l, c), isFoo, "x", l, c),
+			EvNilInteger},
+		{"reduceNil", NewExprReduceMust(NewExprConstant(NewNilExprValue(NewCompositeTypeSignature(VTList, tsInteger)), l, c),
+			NewExprConstant(NewExprValueInteger(0), l, c), max, "x", "acc", l, c),
+			EvNilInteger},
+		{"allNilBody", NewExprLambdaMust(LATAll, NewExprConstant(intList(3, 4), l, c),
+			NewExprConstant(EvNilBoolean, l, c), "x", l, c),
+			EvNilBoolean},
+		{"anyNilBody", NewExprLambdaMust(LATAny, NewExprConstant(intList(3, 4), l, c),
+			NewExprConstant(EvNilBoolean, l, c), "x", l, c),
+			EvNilBoolean},
+		{"allNilBodyFalse", NewExprLambdaMust(LATAll, NewExprConstant(intList(3, 1), l, c),
+			NewExprIf(NewExprCompareMust(CTEqual, intRef("x"), NewExprConstant(NewExprValueInteger(3), l, c), l, c),
+				NewExprConstant(EvNilBoolean, l, c), NewExprConstant(EvBooleanFalse, l, c), l, c), "x", l, c),
+			EvBooleanFalse},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !test.op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", test.op.ResultType(), test.result.Type)
+			}
+			res, err := test.op.Evaluate(newEmptyTestRequestContext())
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestNewExprLambda_Error(t *testing.T) {
+	l, c := 1, 2
+	list := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{}), l, c)
+	str := NewExprConstant(NewExprValueString("foo"), l, c)
+	tests := []struct {
+		name   string
+		lt     LambdaType
+		opList Expression
+		opBody Expression
+	}{
+		{"unknown", LambdaType("unknown"), list, str},
+		{"notList", LATMap, str, str},
+		{"notBooleanBody", LATFilter, list, str},
+		{"reduce", LATReduce, list, str},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprLambda(test.lt, test.opList, test.opBody, "x", l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+	t.Run("reduceBodyType", func(t *testing.T) {
+		_, err := NewExprReduce(list, NewExprConstant(NewExprValueInteger(0), l, c), str, "x", "acc", l, c)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestExprLambda_Dependencies(t *testing.T) {
+	l, c := 1, 2
+	list := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{}), l, c)
+	op := NewExprLambdaMust(LATMap, list, NewExprStringMust(SFJoin, []Expression{
+		NewExprLambdaMust(LATMap, list, NewExprHeapReference("x", "x", l, c), "y", l, c),
+		NewExprHeapReference("x", "x", l, c)}, l, c), "x", l, c)
+	// The inner lambda binds y but reads x written by the outer lambda
+	deps := AnalyzeDependencies(op)
+	if len(deps.InputSet()) != 0 {
+		t.Errorf("wrong inputs.\nactual:   %v\nexpected: []", deps.InputSet())
+	}
+	if len(deps.WriteSet()) != 2 {
+		t.Errorf("wrong writes.\nactual:   %v\nexpected: [x y]", deps.WriteSet())
+	}
+}
@@ -0,0 +1,369 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LetBinding binds a name to the result of an Expression in a let Expression.
+type LetBinding struct {
+	Name  string
+	Value Expression
+}
+
+// exprLet binds a set of names to the results of the binding Expressions and evaluates a body Expression where
+// the names may be referenced (using heap references). The result of the Expression is the result of the body.
+// The bindings are evaluated in order and a binding may reference the names bound before it.
+// The bound names are only visible in the let Expression. That is the bindings are not assigned to the request
+// context. A reference to a bound name (where the key is equal to the name) resolves to the bound value ahead of
+// the request context heap. An assignment to a bound name updates the bound value.
+// Note that each binding Expression is evaluated exactly once even if the name is referenced many times.
+type exprLet struct {
+	baseExpression
+	bindings []LetBinding
+	opBody   Expression
+}
+
+func (op *exprLet) Evaluate(recCtx RequestContext) (Value, error) {
+	letCtx := &letRequestContext{parent: recCtx}
+	for _, binding := range op.bindings {
+		value, err := binding.Value.Evaluate(letCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		letCtx.names = append(letCtx.names, binding.Name)
+		letCtx.values = append(letCtx.values, value)
+	}
+	return op.opBody.Evaluate(letCtx)
+}
+
+func (op *exprLet) String() string {
+	var sb strings.Builder
+	sb.WriteString("(let ")
+	for i, binding := range op.bindings {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(binding.Name)
+		sb.WriteString(" = ")
+		sb.WriteString(binding.Value.String())
+	}
+	sb.WriteString(" in ")
+	sb.WriteString(op.opBody.String())
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func (op *exprLet) Children() []Expression {
+	children := make([]Expression, 0, len(op.bindings)+1)
+	for _, binding := range op.bindings {
+		children = append(children, binding.Value)
+	}
+	return append(children, op.opBody)
+}
+
+func (op *exprLet) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.bindings)+1)
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.bindings = make([]LetBinding, 0, len(op.bindings))
+	for i, binding := range op.bindings {
+		cp.bindings = append(cp.bindings, LetBinding{Name: binding.Name, Value: children[i]})
+	}
+	cp.opBody = children[len(children)-1]
+	return &cp, nil
+}
+
+// names returns the bound names
+func (op *exprLet) names() []string {
+	names := make([]string, 0, len(op.bindings))
+	for _, binding := range op.bindings {
+		names = append(names, binding.Name)
+	}
+	return names
+}
+
+// NewExprLet creates a let Expression. The heap references to a bound name in the body (and in later bindings) get
+// the result type of the binding Expression. The specified Expressions are not modified. Sub-trees holding such
+// references are copied instead (see bindReferences()).
+// The result types of the Expressions holding the references are recomputed (see retypeBound()).
+// An error is returned if no bindings are specified, if a name is bound more than once or if the bound references
+// give an Expression sub-expressions of different result types where a common type is required (e.g. the values of
+// a list). In the latter case use a reference of the correct type (see NewExprTypedHeapReference()) when creating
+// the Expression.
+func NewExprLet(bindings []LetBinding, opBody Expression, line, col int) (Expression, error) {
+	if len(bindings) == 0 {
+		return nil, fmt.Errorf("let without bindings")
+	}
+	seen := make(map[string]bool, len(bindings))
+	for _, binding := range bindings {
+		if binding.Name == "" {
+			return nil, fmt.Errorf("let binding without name")
+		}
+		if seen[binding.Name] {
+			return nil, fmt.Errorf("name %s bound more than once", binding.Name)
+		}
+		seen[binding.Name] = true
+	}
+	// Bind all names visible in an Expression in one pass so that the Expression is retyped (see retypeBound())
+	// only once the types of all its bound references are known
+	bindings = append([]LetBinding(nil), bindings...)
+	names := make(map[string]TypeSignature, len(bindings))
+	for i := range bindings {
+		var err error
+		bindings[i].Value, err = bindReferences(bindings[i].Value, names)
+		if err != nil {
+			return nil, err
+		}
+		names[bindings[i].Name] = bindings[i].Value.ResultType()
+	}
+	opBody, err := bindReferences(opBody, names)
+	if err != nil {
+		return nil, err
+	}
+	return &exprLet{
+		baseExpression: newBaseExpression(opBody.ResultType(), line, col),
+		bindings:       bindings,
+		opBody:         opBody,
+	}, nil
+}
+
+func NewExprLetMust(bindings []LetBinding, opBody Expression, line, col int) Expression {
+	expr, err := NewExprLet(bindings, opBody, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating let expression: %v", err))
+	}
+	return expr
+}
+
+// bindReferences returns the expression tree where the heap references to the bound names have the result types
+// of the names. The references in a nested let (or try) Expression binding the same name are not changed. The
+// expression tree is not modified. A copy (see Expression.WithChildren()) is returned for each expression holding a
+// changed reference. If the result type of a sub-expression changes the copy is retyped (see retypeBound()).
+func bindReferences(expr Expression, names map[string]TypeSignature) (Expression, error) {
+	if len(names) == 0 {
+		return expr, nil
+	}
+	orig := expr.Children()
+	children := append([]Expression(nil), orig...)
+	// The names visible in each child (in evaluation order)
+	scopes := make([]map[string]TypeSignature, len(children))
+	for i := range scopes {
+		scopes[i] = names
+	}
+	switch op := expr.(type) {
+	case *exprReference:
+		if ts, ok := names[fmt.Sprint(op.key)]; ok && op.source == RSHeap {
+			cp := *op
+			cp.resType = ts
+			return &cp, nil
+		}
+	case *exprLet:
+		// A name bound by the let is shadowed from the next binding
+		for i, binding := range op.bindings {
+			if _, ok := names[binding.Name]; ok {
+				for j := i + 1; j < len(scopes); j++ {
+					scopes[j] = withoutName(scopes[j], binding.Name)
+				}
+			}
+		}
+	case *exprTry:
+		// The error name is shadowed in the catch Expression
+		if _, ok := names[op.errName]; ok {
+			scopes[1] = withoutName(names, op.errName)
+		}
+	}
+	changed, retype := false, false
+	for i, child := range children {
+		var err error
+		children[i], err = bindReferences(child, scopes[i])
+		if err != nil {
+			return nil, err
+		}
+		changed = changed || children[i] != child
+		retype = retype || !children[i].ResultType().Equal(orig[i].ResultType())
+	}
+	if !changed {
+		return expr, nil
+	}
+	cp, err := expr.WithChildren(children)
+	if err != nil {
+		panic(fmt.Sprintf("can't bind references of %v: %v", expr, err))
+	}
+	if retype {
+		return retypeBound(cp)
+	}
+	return cp, nil
+}
+
+// withoutName returns a copy of the bound names without the name
+func withoutName(names map[string]TypeSignature, name string) map[string]TypeSignature {
+	cp := make(map[string]TypeSignature, len(names))
+	for n, ts := range names {
+		if n != name {
+			cp[n] = ts
+		}
+	}
+	return cp
+}
+
+// retypeBound recomputes the result type of an Expression copied by bindReferences() where the result type of a
+// sub-expression has changed. The Expression is modified (it must be a copy). An error is returned if the
+// sub-expressions no longer have a common result type where one is required (e.g. the values of a list).
+// The result type of the other Expressions doesn't depend on the result types of the sub-expressions.
+func retypeBound(expr Expression) (Expression, error) {
+	var err error
+	switch op := expr.(type) {
+	case *exprAssign:
+		op.resType = op.valueOp.ResultType()
+	case *exprFor:
+		if !op.opList.ResultType().IsValueType(VTList) {
+			return nil, fmt.Errorf("foreach expects a list (got %v)", op.opList.ResultType())
+		}
+		op.resType = *op.opList.ResultType().UnitType
+	case *exprIf:
+		op.resType = op.thenOp.ResultType()
+	case *exprLambda:
+		switch op.lt {
+		case LATFilter:
+			op.resType = op.opList.ResultType()
+		case LATMap:
+			op.resType = NewCompositeTypeSignature(VTList, op.opBody.ResultType())
+		case LATReduce:
+			op.resType, err = commonResultType(string(op.lt), []Expression{op.opInit, op.opBody})
+		}
+	case *exprLet:
+		// The references to the names bound by the let get the new result types of the bindings
+		return NewExprLet(op.bindings, op.opBody, op.Line(), op.Col())
+	case *exprList:
+		var ut TypeSignature
+		ut, err = commonResultType("list", op.ops)
+		op.resType = NewCompositeTypeSignature(VTList, ut)
+	case *exprMap:
+		var ut TypeSignature
+		ut, err = commonResultType("map", op.ops)
+		op.resType = NewCompositeTypeSignature(VTMap, ut)
+	case *exprNil:
+		if op.no != NOIsNil {
+			op.resType, err = commonResultType(string(op.no), op.ops)
+		}
+	case *exprReference:
+		if op.source == RSValue {
+			op.resType = valueReferenceType(op.key, op.sourceOp.ResultType())
+		}
+	case *exprSequence:
+		op.resType = op.ops[len(op.ops)-1].ResultType()
+	case *exprSwitch:
+		results := make([]Expression, 0, len(op.arms)+1)
+		for _, arm := range op.arms {
+			results = append(results, arm.Result)
+		}
+		if op.opDefault != nil {
+			results = append(results, op.opDefault)
+		}
+		op.resType, err = commonResultType("switch", results)
+	case *exprTry:
+		op.resType, err = commonResultType("try", []Expression{op.opTry, op.opCatch})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v (use a typed reference)", err)
+	}
+	return expr, nil
+}
+
+// commonResultType returns the common result type of the Expressions. Contrary to unitType() the Expressions are
+// never adapted. If no Expressions are specified the default type is returned.
+func commonResultType(name string, ops []Expression) (TypeSignature, error) {
+	if len(ops) == 0 {
+		return TsDefault, nil
+	}
+	for _, op := range ops[1:] {
+		if !op.ResultType().Equal(ops[0].ResultType()) {
+			return TypeSignature{}, fmt.Errorf("%s with different value types (%v != %v)", name,
+				ops[0].ResultType(), op.ResultType())
+		}
+	}
+	return ops[0].ResultType(), nil
+}
+
+// letRequestContext is the request context used when evaluating the bindings and the body of a let Expression.
+// Bound names are resolved by the context. All other keys are resolved by the parent context.
+type letRequestContext struct {
+	parent RequestContext
+	names  []string
+	values []Value
+}
+
+// lookup returns the index of the binding for the key. If the key is not bound -1 is returned.
+func (lc *letRequestContext) lookup(key interface{}) int {
+	name := fmt.Sprint(key)
+	for i := len(lc.names) - 1; i >= 0; i-- {
+		if lc.names[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (lc *letRequestContext) Reference(key interface{}, ts TypeSignature) (Value, error) {
+	i := lc.lookup(key)
+	if i < 0 {
+		return lc.parent.Reference(key, ts)
+	}
+	return lc.values[i], nil
+}
+
+func (lc *letRequestContext) Assign(key interface{}, value Value) error {
+	i := lc.lookup(key)
+	if i < 0 {
+		return lc.parent.Assign(key, value)
+	}
+	lc.values[i] = value
+	return nil
+}
+
+// letScope keeps track of the names bound by the enclosing let (and try) Expressions when traversing an expression
+// tree.
+type letScope map[string]int
+
+// bound returns true if the key is bound by an enclosing let Expression
+func (ls letScope) bound(key interface{}) bool {
+	return ls[fmt.Sprint(key)] > 0
+}
+
+// inspectScoped traverses the expression tree (in the same order as Inspect()) and calls f for each expression
+// with the names bound by the enclosing let (and try) Expressions.
+func inspectScoped(expr Expression, f func(e Expression, scope letScope)) {
+	scope := make(letScope)
+	var inspect func(e Expression)
+	inspect = func(e Expression) {
+		f(e, scope)
+		switch op := e.(type) {
+		case *exprLet:
+			for _, binding := range op.bindings {
+				inspect(binding.Value)
+				scope[binding.Name]++
+			}
+			inspect(op.opBody)
+			for _, binding := range op.bindings {
+				scope[binding.Name]--
+			}
+		case *exprTry:
+			inspect(op.opTry)
+			if op.errName == "" {
+				inspect(op.opCatch)
+				break
+			}
+			scope[op.errName]++
+			inspect(op.opCatch)
+			scope[op.errName]--
+		default:
+			for _, child := range e.Children() {
+				inspect(child)
+			}
+		}
+	}
+	inspect(expr)
+}
@@ -0,0 +1,263 @@
+package goexpr
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExprLet_String(t *testing.T) {
+	l, c := 1, 2
+	op := NewExprLetMust([]LetBinding{
+		{Name: "x", Value: NewExprConstant(NewExprValueInteger(1), l, c)},
+		{Name: "y", Value: NewExprHeapReference("x", "x", l, c)},
+	}, NewExprCompareMust(CTEqual, NewExprHeapReference("x", "x", l, c), NewExprHeapReference("y", "y", l, c), l, c),
+		l, c)
+	expected := `(let x = 1, y = x in (x == y))`
+	if op.String() != expected {
+		t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", op.String(), expected)
+	}
+	formatted := Format(op, FormatOptions{Width: 14, Indent: 2})
+	expected = "(let x = 1,\n  y = x\n  in (x == y))"
+	if formatted != expected {
+		t.Errorf("wrong format result.\nactual:   %v\nexpected: %v", formatted, expected)
+	}
+}
+
+func TestExprLet_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	tsStringList := NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString))
+	ref := func(name string) Expression {
+		return NewExprHeapReference(name, name, l, c)
+	}
+	integer := func(i int) Expression {
+		return NewExprConstant(NewExprValueInteger(i), l, c)
+	}
+	str := func(s string) Expression {
+		return NewExprConstant(NewExprValueString(s), l, c)
+	}
+	tests := []struct {
+		name   string
+		op     Expression
+		result Value
+	}{
+		{"bodyReference", NewExprLetMust([]LetBinding{{Name: "x", Value: integer(5)}}, ref("x"), l, c),
+			NewExprValueInteger(5)},
+		{"bindingReference", NewExprLetMust([]LetBinding{
+			{Name: "x", Value: integer(5)},
+			{Name: "y", Value: NewExprCompareMust(CTGreater, ref("x"), integer(3), l, c)},
+		}, ref("y"), l, c),
+			EvBooleanTrue},
+		{"aheadOfHeap", NewExprLetMust([]LetBinding{{Name: "name", Value: str("bound")}}, ref("name"), l, c),
+			NewExprValueString("bound")},
+		{"heapReference", NewExprLetMust([]LetBinding{{Name: "x", Value: str("bound")}}, ref("name"), l, c),
+			NewExprValueString("heap")},
+		{"shadowing", NewExprLetMust([]LetBinding{{Name: "x", Value: integer(1)}},
+			NewExprSequence([]Expression{
+				NewExprLetMust([]LetBinding{{Name: "x", Value: str("inner")}}, ref("x"), l, c),
+				NewExprTypedHeapReference("x", "x", tsInteger, l, c),
+			}, l, c), l, c),
+			NewExprValueInteger(1)},
+		{"compositeBinding", NewExprLetMust([]LetBinding{
+			{Name: "parts", Value: NewExprStringMust(SFSplit, []Expression{str("a,b"), str(",")}, l, c)},
+		}, NewExprStringMust(SFJoin, []Expression{
+			NewExprTypedHeapReference("parts", "parts", tsStringList, l, c), str("-")}, l, c), l, c),
+			NewExprValueString("a-b")},
+		{"assign", NewExprLetMust([]LetBinding{{Name: "x", Value: integer(1)}},
+			NewExprSequence([]Expression{
+				NewExprAssign("x", "x", integer(2), nil, RSHeap, l, c),
+				NewExprTypedHeapReference("x", "x", tsInteger, l, c),
+			}, l, c), l, c),
+			NewExprValueInteger(2)},
+		{"nilBinding", NewExprLetMust([]LetBinding{{Name: "x", Value: NewExprConstant(EvNilInteger, l, c)}},
+			ref("x"), l, c),
+			NewNilExprValue(tsInteger)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reqCtx := newTestRequestContext(map[string]string{"name": "heap"})
+			if !test.op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", test.op.ResultType(), test.result.Type)
+			}
+			res, err := test.op.Evaluate(reqCtx)
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+			// The bindings must not leak to the request context
+			values := reqCtx.(testRequestContext).Values
+			if !reflect.DeepEqual(values, map[string]string{"name": "heap"}) {
+				t.Errorf("request context changed: %v", values)
+			}
+		})
+	}
+}
+
+func TestExprLet_EvaluateOnce(t *testing.T) {
+	l, c := 1, 2
+	calls := 0
+	registry := NewFunctionRegistry()
+	err := registry.Register(Function{
+		Name:   "expensive",
+		Result: NewScalarTypeSignature(VTInteger),
+		Impl: func(args []Value) (Value, error) {
+			calls++
+			return NewExprValueInteger(42), nil
+		},
+	})
+	if err != nil {
+		t.Errorf("unexpected error registering function: %v", err)
+		return
+	}
+	ref := NewExprHeapReference("x", "x", l, c)
+	op := NewExprLetMust([]LetBinding{
+		{Name: "x", Value: NewExprCallMust(registry, "expensive", nil, l, c)},
+	}, NewExprCompareMust(CTEqual, ref, ref, l, c), l, c)
+	res, err := op.Evaluate(newEmptyTestRequestContext())
+	if err != nil {
+		t.Errorf("unexprected evaluation error: %v", err)
+		return
+	}
+	if !res.Equal(EvBooleanTrue) || calls != 1 {
+		t.Errorf("wrong evaluation.\nactual:   %v (%d calls)\nexpected: true (1 call)", res, calls)
+	}
+}
+
+func TestNewExprLet_Unmodified(t *testing.T) {
+	l, c := 1, 2
+	// The body (shared with another expression) is not modified when the references are bound
+	ref := NewExprHeapReference("x", "x", l, c)
+	body := NewExprSequence([]Expression{ref}, l, c)
+	op := NewExprLetMust([]LetBinding{
+		{Name: "x", Value: NewExprConstant(NewExprValueInteger(1), l, c)},
+	}, body, l, c)
+	if !ref.ResultType().Equal(TsDefault) {
+		t.Errorf("wrong reference type.\nactual:   %v\nexpected: %v", ref.ResultType(), TsDefault)
+	}
+	if body.Children()[0] != ref {
+		t.Errorf("body modified by let")
+	}
+	bound := op.Children()[1].Children()[0]
+	if !bound.ResultType().Equal(NewScalarTypeSignature(VTInteger)) {
+		t.Errorf("wrong bound reference type.\nactual:   %v\nexpected: {integer}", bound.ResultType())
+	}
+}
+
+func TestNewExprLet_ResultType(t *testing.T) {
+	l, c := 1, 2
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	ref := func(name string) Expression {
+		return NewExprHeapReference(name, name, l, c)
+	}
+	integer := func(i int) Expression {
+		return NewExprConstant(NewExprValueInteger(i), l, c)
+	}
+	xs := LetBinding{Name: "xs", Value: NewExprListMust([]Expression{integer(7)}, l, c)}
+	n := LetBinding{Name: "n", Value: integer(5)}
+	tests := []struct {
+		name string
+		op   Expression
+		ts   TypeSignature
+	}{
+		{"index", NewExprLetMust([]LetBinding{xs}, NewExprValueReference("xs", 0, ref("xs"), l, c), l, c), tsInteger},
+		{"sequence", NewExprLetMust([]LetBinding{n}, NewExprSequence([]Expression{ref("n")}, l, c), l, c),
+			tsInteger},
+		{"if", NewExprLetMust([]LetBinding{n}, NewExprIf(NewExprConstant(EvBooleanTrue, l, c), ref("n"), ref("n"),
+			l, c), l, c), tsInteger},
+		{"list", NewExprLetMust([]LetBinding{n}, NewExprListMust([]Expression{ref("n"), ref("n")}, l, c), l, c),
+			NewCompositeTypeSignature(VTList, tsInteger)},
+		{"nestedLet", NewExprLetMust([]LetBinding{xs}, NewExprLetMust([]LetBinding{
+			{Name: "y", Value: NewExprValueReference("xs", 0, ref("xs"), l, c)}}, ref("y"), l, c), l, c), tsInteger},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := test.op.Evaluate(newEmptyTestRequestContext())
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !test.op.ResultType().Equal(test.ts) || !res.Type.Equal(test.ts) {
+				t.Errorf("wrong result type.\nactual:   %v (evaluated %v)\nexpected: %v", test.op.ResultType(),
+					res.Type, test.ts)
+			}
+		})
+	}
+	t.Run("differentTypes", func(t *testing.T) {
+		// The list values get different types when n is bound
+		list := NewExprListMust([]Expression{ref("n"), NewExprConstant(NewExprValueString("x"), l, c)}, l, c)
+		_, err := NewExprLet([]LetBinding{n}, list, l, c)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestNewExprLet_Error(t *testing.T) {
+	l, c := 1, 2
+	str := NewExprConstant(NewExprValueString("foo"), l, c)
+	tests := []struct {
+		name     string
+		bindings []LetBinding
+	}{
+		{"noBindings", []LetBinding{}},
+		{"noName", []LetBinding{{Name: "", Value: str}}},
+		{"duplicateName", []LetBinding{{Name: "x", Value: str}, {Name: "x", Value: str}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprLet(test.bindings, str, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestExprLet_Keys(t *testing.T) {
+	l, c := 1, 2
+	op := NewExprLetMust([]LetBinding{
+		{Name: "x", Value: NewExprHeapReference("a", "a", l, c)},
+	}, NewExprSequence([]Expression{
+		NewExprAssign("x", "x", NewExprHeapReference("b", "b", l, c), nil, RSHeap, l, c),
+		NewExprAssign("y", "y", NewExprHeapReference("x", "x", l, c), nil, RSHeap, l, c),
+	}, l, c), l, c)
+	keys := ReferencedKeys(op)
+	if !reflect.DeepEqual(keys, []interface{}{"a", "b"}) {
+		t.Errorf("wrong referenced keys.\nactual:   %v\nexpected: [a b]", keys)
+	}
+	keys = AssignedKeys(op)
+	if !reflect.DeepEqual(keys, []interface{}{"y"}) {
+		t.Errorf("wrong assigned keys.\nactual:   %v\nexpected: [y]", keys)
+	}
+	deps := AnalyzeDependencies(op)
+	if len(deps.Reads) != 2 || len(deps.Writes) != 1 || len(deps.InputSet()) != 2 {
+		t.Errorf("wrong dependencies.\nreads:  %v\nwrites: %v", deps.Reads, deps.Writes)
+	}
+}
+
+func TestExprLet_LoopKeys(t *testing.T) {
+	l, c := 1, 2
+	list := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{NewExprValueInteger(1)}), l, c)
+	item := NewExprTypedHeapReference("i", "i", NewScalarTypeSignature(VTInteger), l, c)
+	op := NewExprLetMust([]LetBinding{
+		{Name: "i", Value: NewExprConstant(NewExprValueInteger(0), l, c)},
+		{Name: "acc", Value: NewExprConstant(NewExprValueInteger(0), l, c)},
+	}, NewExprSequence([]Expression{
+		NewExprFor(list, item, nil, "i", l, c),
+		NewExprLambdaMust(LATMap, list, item, "i", l, c),
+		NewExprReduceMust(list, NewExprConstant(NewExprValueInteger(0), l, c), item, "j", "acc", l, c),
+		NewExprSortMust(list, item, "i", false, l, c),
+	}, l, c), l, c)
+	// The loop keys are bound by the let (except j)
+	keys := AssignedKeys(op)
+	if !reflect.DeepEqual(keys, []interface{}{"j"}) {
+		t.Errorf("wrong assigned keys.\nactual:   %v\nexpected: [j]", keys)
+	}
+	deps := AnalyzeDependencies(op)
+	if len(deps.Writes) != len(keys) {
+		t.Errorf("assigned keys and dependencies differ.\nkeys:   %v\nwrites: %v", keys, deps.Writes)
+	}
+}
@@ -0,0 +1,199 @@
+package goexpr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// exprList creates a list from the results of a set of Expressions. All Expressions must have the same result type
+// which is the unit type of the list.
+// Nil values are included in the list as is. If the list is empty an empty list is returned.
+type exprList struct {
+	baseExpression
+	ops []Expression
+}
+
+func (op *exprList) Evaluate(recCtx RequestContext) (Value, error) {
+	list := make([]Value, 0, len(op.ops))
+	for _, subOp := range op.ops {
+		value, err := subOp.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		list = append(list, value)
+	}
+	return NewExprValueList(*op.ResultType().UnitType, list), nil
+}
+
+func (op *exprList) String() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i, subOp := range op.ops {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(subOp.String())
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func (op *exprList) Children() []Expression {
+	return append([]Expression(nil), op.ops...)
+}
+
+func (op *exprList) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.ops))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.ops = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
+// NewExprList creates a list constructor Expression. The unit type of the list is inferred from the Expressions.
+// An error is returned if the Expressions don't have the same result type. If no Expressions are specified the
+// unit type is the default type.
+func NewExprList(ops []Expression, line, col int) (Expression, error) {
+	ut, err := unitType("list", ops)
+	if err != nil {
+		return nil, err
+	}
+	return &exprList{
+		baseExpression: newBaseExpression(NewCompositeTypeSignature(VTList, ut), line, col),
+		ops:            ops,
+	}, nil
+}
+
+func NewExprListMust(ops []Expression, line, col int) Expression {
+	expr, err := NewExprList(ops, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating list expression: %v", err))
+	}
+	return expr
+}
+
+// exprMap creates a map from a set of keys and the results of a set of Expressions (one for each key). All
+// Expressions must have the same result type which is the unit type of the map.
+// Nil values are included in the map as is. If the map is empty an empty map is returned.
+type exprMap struct {
+	baseExpression
+	// The map keys sorted (the Expressions are evaluated in key order)
+	keys []string
+	ops  []Expression
+}
+
+func (op *exprMap) Evaluate(recCtx RequestContext) (Value, error) {
+	mp := make(map[string]Value, len(op.ops))
+	for i, subOp := range op.ops {
+		value, err := subOp.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		mp[op.keys[i]] = value
+	}
+	return NewExprValueMap(*op.ResultType().UnitType, mp), nil
+}
+
+func (op *exprMap) String() string {
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i, subOp := range op.ops {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(op.keys[i])
+		sb.WriteString(":")
+		sb.WriteString(subOp.String())
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (op *exprMap) Children() []Expression {
+	return append([]Expression(nil), op.ops...)
+}
+
+func (op *exprMap) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.ops))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.ops = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
+// NewExprMap creates a map constructor Expression. The value of keys[i] is the result of ops[i]. The unit type of
+// the map is inferred from the Expressions. An error is returned if the number of keys and Expressions differ, if
+// a key is duplicated or if the Expressions don't have the same result type. If no Expressions are specified the
+// unit type is the default type.
+func NewExprMap(keys []string, ops []Expression, line, col int) (Expression, error) {
+	if len(keys) != len(ops) {
+		return nil, fmt.Errorf("map with %d keys and %d values", len(keys), len(ops))
+	}
+	// Keep the entries sorted by key to get the same order as for a map value
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return keys[order[i]] < keys[order[j]]
+	})
+	sortedKeys := make([]string, 0, len(keys))
+	sortedOps := make([]Expression, 0, len(ops))
+	for _, i := range order {
+		if len(sortedKeys) > 0 && sortedKeys[len(sortedKeys)-1] == keys[i] {
+			return nil, fmt.Errorf("map with duplicate key %s", keys[i])
+		}
+		sortedKeys = append(sortedKeys, keys[i])
+		sortedOps = append(sortedOps, ops[i])
+	}
+	ut, err := unitType("map", sortedOps)
+	if err != nil {
+		return nil, err
+	}
+	return &exprMap{
+		baseExpression: newBaseExpression(NewCompositeTypeSignature(VTMap, ut), line, col),
+		keys:           sortedKeys,
+		ops:            sortedOps,
+	}, nil
+}
+
+func NewExprMapMust(keys []string, ops []Expression, line, col int) Expression {
+	expr, err := NewExprMap(keys, ops, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating map expression: %v", err))
+	}
+	return expr
+}
+
+// unitType returns the common result type of the Expressions. The result type of each Expression is tried in
+// turn so that "dynamically typed" Expressions (e.g. reference) adapt to the type of the other Expressions.
+// If no Expressions are specified the default type is returned. The Expressions are only adapted when a common type
+// is found. That is the Expressions are left unchanged if an error is returned.
+func unitType(name string, ops []Expression) (TypeSignature, error) {
+	if len(ops) == 0 {
+		return TsDefault, nil
+	}
+	for _, candidate := range ops {
+		ut := candidate.ResultType()
+		ok := true
+		for _, op := range ops {
+			if !acceptsResultType(op, ut) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			for _, op := range ops {
+				op.ExpectedResultType(ut)
+			}
+			return ut, nil
+		}
+	}
+	return TypeSignature{}, fmt.Errorf("%s with different value types (%v != %v)", name, ops[0].ResultType(),
+		ops[len(ops)-1].ResultType())
+}
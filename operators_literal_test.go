@@ -0,0 +1,152 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func TestExprLiteral_String(t *testing.T) {
+	l, c := 1, 2
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"list", NewExprListMust([]Expression{
+			NewExprHeapReference("email", "email", l, c),
+			NewExprConstant(NewExprValueString("foo@bar.com"), l, c)}, l, c),
+			`[email,"foo@bar.com"]`},
+		{"listEmpty", NewExprListMust([]Expression{}, l, c), `[]`},
+		{"map", NewExprMapMust([]string{"b", "a"}, []Expression{
+			NewExprConstant(NewExprValueInteger(2), l, c),
+			NewExprConstant(NewExprValueInteger(1), l, c)}, l, c),
+			`{a:1,b:2}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprLiteral_StringValue(t *testing.T) {
+	l, c := 1, 2
+	integer := func(i int) Expression {
+		return NewExprConstant(NewExprValueInteger(i), l, c)
+	}
+	// A literal of constants has the same string representation as the value it evaluates to
+	for _, op := range []Expression{
+		NewExprListMust([]Expression{integer(1), integer(2)}, l, c),
+		NewExprMapMust([]string{"b", "a"}, []Expression{integer(2), integer(1)}, l, c),
+	} {
+		res, err := op.Evaluate(newEmptyTestRequestContext())
+		if err != nil {
+			t.Errorf("unexprected evaluation error: %v", err)
+			continue
+		}
+		if op.String() != res.String() {
+			t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", op.String(), res.String())
+		}
+	}
+}
+
+func TestExprLiteral_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	reqCtx := newTestRequestContext(map[string]string{"email": "foo@bar.com", "age": "42"})
+	tsString := NewScalarTypeSignature(VTString)
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	str := func(s string) Expression {
+		return NewExprConstant(NewExprValueString(s), l, c)
+	}
+	tests := []struct {
+		name   string
+		op     Expression
+		result Value
+	}{
+		{"list", NewExprListMust([]Expression{
+			NewExprHeapReference("email", "email", l, c), str("baz@bar.com")}, l, c),
+			NewExprValueList(tsString, []Value{NewExprValueString("foo@bar.com"), NewExprValueString("baz@bar.com")})},
+		{"listAdaptReference", NewExprListMust([]Expression{
+			NewExprHeapReference("age", "age", l, c), NewExprConstant(NewExprValueInteger(1), l, c)}, l, c),
+			NewExprValueList(tsInteger, []Value{NewExprValueInteger(42), NewExprValueInteger(1)})},
+		{"listNilValue", NewExprListMust([]Expression{
+			str("foo"), NewExprHeapReference("missing", "missing", l, c)}, l, c),
+			NewExprValueList(tsString, []Value{NewExprValueString("foo"), EvNilString})},
+		{"listEmpty", NewExprListMust([]Expression{}, l, c),
+			NewExprValueList(TsDefault, []Value{})},
+		{"listOfLists", NewExprListMust([]Expression{
+			NewExprListMust([]Expression{str("a")}, l, c), NewExprListMust([]Expression{}, l, c)}, l, c),
+			NewExprValueList(NewCompositeTypeSignature(VTList, tsString), []Value{
+				NewExprValueList(tsString, []Value{NewExprValueString("a")}),
+				NewExprValueList(tsString, []Value{})})},
+		{"map", NewExprMapMust([]string{"email", "alt"}, []Expression{
+			NewExprHeapReference("email", "email", l, c), str("baz@bar.com")}, l, c),
+			NewExprValueMap(tsString, map[string]Value{
+				"email": NewExprValueString("foo@bar.com"),
+				"alt":   NewExprValueString("baz@bar.com")})},
+		{"mapEmpty", NewExprMapMust([]string{}, []Expression{}, l, c),
+			NewExprValueMap(TsDefault, map[string]Value{})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !test.op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", test.op.ResultType(), test.result.Type)
+			}
+			res, err := test.op.Evaluate(reqCtx)
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+			if res.String() != test.result.String() {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", res.String(), test.result.String())
+			}
+		})
+	}
+}
+
+func TestNewExprLiteral_Error(t *testing.T) {
+	l, c := 1, 2
+	str := NewExprConstant(NewExprValueString("foo"), l, c)
+	integer := NewExprConstant(NewExprValueInteger(1), l, c)
+	t.Run("listDifferentTypes", func(t *testing.T) {
+		_, err := NewExprList([]Expression{str, integer}, l, c)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+	t.Run("mapDifferentTypes", func(t *testing.T) {
+		_, err := NewExprMap([]string{"a", "b"}, []Expression{str, integer}, l, c)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+	t.Run("listErrorUnchanged", func(t *testing.T) {
+		// A reference is not adapted when there is no common type
+		ref := NewExprHeapReference("x", "x", l, c)
+		list := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{}), l, c)
+		_, err := NewExprList([]Expression{ref, integer, list}, l, c)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+		if !ref.ResultType().Equal(TsDefault) {
+			t.Errorf("wrong reference type.\nactual:   %v\nexpected: %v", ref.ResultType(), TsDefault)
+		}
+	})
+	t.Run("mapDuplicateKey", func(t *testing.T) {
+		_, err := NewExprMap([]string{"a", "a"}, []Expression{str, str}, l, c)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+	t.Run("mapKeyCount", func(t *testing.T) {
+		_, err := NewExprMap([]string{"a"}, []Expression{str, str}, l, c)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
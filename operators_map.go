@@ -0,0 +1,222 @@
+package goexpr
+
+import (
+	"fmt"
+)
+
+// Map function type
+type MapFunction string
+
+const (
+	MFDelete  MapFunction = "delete"
+	MFEntries MapFunction = "entries"
+	MFHasKey  MapFunction = "hasKey"
+	MFKeys    MapFunction = "keys"
+	MFMerge   MapFunction = "merge"
+	MFValues  MapFunction = "values"
+)
+
+// Merge conflict resolution. Decides the value to use when both maps of a merge contain the same key.
+type MergeConflict string
+
+const (
+	// Fail the evaluation
+	MCError MergeConflict = "error"
+	// Use the value of the first map
+	MCFirst MergeConflict = "first"
+	// Use the value of the last map
+	MCLast MergeConflict = "last"
+)
+
+// exprMapFunction applies a map function to a map (and possibly a second argument). Keys and values are returned
+// in key order (see sortedKeys()). Maps are never modified. A new map is returned instead.
+// keys <map> => list of strings (the keys of the map)
+// values <map> => list (the values of the map)
+// entries <map> => list of maps of any (one map for each entry in the map with the key "key" holding the entry key
+// and the key "value" holding the entry value, e.g. {"key": "a", "value": 1})
+// hasKey <map> <string> => boolean (true if the map contains the key)
+// delete <map> <string> => map (the map without the key)
+// merge <map1> <map2> => map (the entries in both maps where the merge conflict resolution decides the value for
+// a key in both maps). An error is returned if the conflict resolution is MCError and the maps have a common key.
+// The maps of merge must be of the same type and a returned map has the same type as the (first) map.
+// If one of the arguments evaluates to nil then nil is returned. That is the map Expression propagates nil.
+type exprMapFunction struct {
+	baseExpression
+	mf   MapFunction
+	args []Expression
+	// The merge conflict resolution (merge only)
+	conflict MergeConflict
+}
+
+func (op *exprMapFunction) Evaluate(recCtx RequestContext) (Value, error) {
+	args := make([]Value, 0, len(op.args))
+	for _, argOp := range op.args {
+		arg, err := argOp.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		// Map functions propagates nil
+		if arg.Nil() {
+			return op.nilResult(), nil
+		}
+		args = append(args, arg)
+	}
+
+	valueMap := args[0].Value.(map[string]Value)
+	unit := *args[0].Type.UnitType
+	switch op.mf {
+	case MFDelete:
+		key := args[1].Value.(string)
+		result := make(map[string]Value, len(valueMap))
+		for k, v := range valueMap {
+			if k != key {
+				result[k] = v
+			}
+		}
+		return NewExprValueMap(unit, result), nil
+	case MFEntries:
+		entries := make([]Value, 0, len(valueMap))
+		for _, key := range sortedKeys(valueMap) {
+			entries = append(entries, NewExprValueMap(NewScalarTypeSignature(VTAny), map[string]Value{
+				"key":   NewExprValueAny(NewExprValueString(key)),
+				"value": NewExprValueAny(valueMap[key]),
+			}))
+		}
+		return NewExprValueList(*op.ResultType().UnitType, entries), nil
+	case MFHasKey:
+		_, ok := valueMap[args[1].Value.(string)]
+		return NewExprValueBoolean(ok), nil
+	case MFKeys:
+		keys := make([]Value, 0, len(valueMap))
+		for _, key := range sortedKeys(valueMap) {
+			keys = append(keys, NewExprValueString(key))
+		}
+		return NewExprValueList(NewScalarTypeSignature(VTString), keys), nil
+	case MFMerge:
+		result := make(map[string]Value, len(valueMap))
+		for k, v := range valueMap {
+			result[k] = v
+		}
+		// Merge in key order to make the reported conflicting key deterministic
+		lastMap := args[1].Value.(map[string]Value)
+		for _, k := range sortedKeys(lastMap) {
+			v := lastMap[k]
+			if _, ok := result[k]; ok {
+				switch op.conflict {
+				case MCError:
+					return op.nilResult(), NewEvaluationError(EKOther,
+						fmt.Errorf("merge conflict for key %s", k), op.Line(), op.Col())
+				case MCFirst:
+					continue
+				}
+			}
+			result[k] = v
+		}
+		return NewExprValueMap(unit, result), nil
+	case MFValues:
+		return NewExprValueList(unit, args[0].iterationValues()), nil
+	default:
+		panic(fmt.Sprintf("unknown map function %v", op.mf))
+	}
+}
+
+// name returns the name of the map function including the merge conflict resolution (e.g. "merge first")
+func (op *exprMapFunction) name() string {
+	if op.mf == MFMerge {
+		return fmt.Sprintf("%s %s", op.mf, op.conflict)
+	}
+	return string(op.mf)
+}
+
+func (op *exprMapFunction) String() string {
+	return functionString(op.name(), op.args)
+}
+
+func (op *exprMapFunction) Children() []Expression {
+	return append([]Expression(nil), op.args...)
+}
+
+func (op *exprMapFunction) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.args))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.args = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
+// NewExprMapFunction creates a map function Expression (except merge, see NewExprMerge()). An error is returned
+// if the map function is unknown or if the arguments don't match the parameters of the map function.
+func NewExprMapFunction(mf MapFunction, args []Expression, line, col int) (Expression, error) {
+	if mf == MFMerge {
+		return nil, fmt.Errorf("use NewExprMerge to create a merge expression")
+	}
+	return newExprMapFunction(mf, args, MCLast, line, col)
+}
+
+func NewExprMapFunctionMust(mf MapFunction, args []Expression, line, col int) Expression {
+	expr, err := NewExprMapFunction(mf, args, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating map expression: %v", err))
+	}
+	return expr
+}
+
+// NewExprMerge creates an Expression merging two maps using the specified merge conflict resolution.
+// An error is returned if the conflict resolution is unknown or if the Expressions are not maps of the same type.
+func NewExprMerge(opFirst, opLast Expression, conflict MergeConflict, line, col int) (Expression, error) {
+	switch conflict {
+	case MCError, MCFirst, MCLast:
+	default:
+		return nil, fmt.Errorf("unknown merge conflict resolution %v", conflict)
+	}
+	return newExprMapFunction(MFMerge, []Expression{opFirst, opLast}, conflict, line, col)
+}
+
+func NewExprMergeMust(opFirst, opLast Expression, conflict MergeConflict, line, col int) Expression {
+	expr, err := NewExprMerge(opFirst, opLast, conflict, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating merge expression: %v", err))
+	}
+	return expr
+}
+
+func newExprMapFunction(mf MapFunction, args []Expression, conflict MergeConflict, line, col int) (Expression,
+	error) {
+	if len(args) == 0 || !args[0].ResultType().IsValueType(VTMap) {
+		return nil, fmt.Errorf("%s expects a map as first argument", mf)
+	}
+	ts := args[0].ResultType()
+	unit := *ts.UnitType
+	tsString := NewScalarTypeSignature(VTString)
+	var params []TypeSignature
+	var rt TypeSignature
+	switch mf {
+	case MFDelete:
+		params, rt = []TypeSignature{ts, tsString}, ts
+	case MFEntries:
+		params, rt = []TypeSignature{ts}, NewCompositeTypeSignature(VTList,
+			NewCompositeTypeSignature(VTMap, NewScalarTypeSignature(VTAny)))
+	case MFHasKey:
+		params, rt = []TypeSignature{ts, tsString}, NewScalarTypeSignature(VTBoolean)
+	case MFKeys:
+		params, rt = []TypeSignature{ts}, NewCompositeTypeSignature(VTList, tsString)
+	case MFMerge:
+		params, rt = []TypeSignature{ts, ts}, ts
+	case MFValues:
+		params, rt = []TypeSignature{ts}, NewCompositeTypeSignature(VTList, unit)
+	default:
+		return nil, fmt.Errorf("unknown map function %v", mf)
+	}
+	err := checkArguments(string(mf), args, params)
+	if err != nil {
+		return nil, err
+	}
+	return &exprMapFunction{
+		baseExpression: newBaseExpression(rt, line, col),
+		mf:             mf,
+		args:           args,
+		conflict:       conflict,
+	}, nil
+}
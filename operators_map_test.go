@@ -0,0 +1,200 @@
+package goexpr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExprMapFunction_String(t *testing.T) {
+	l, c := 1, 2
+	tsStringMap := NewCompositeTypeSignature(VTMap, NewScalarTypeSignature(VTString))
+	m1 := NewExprTypedHeapReference("m1", "m1", tsStringMap, l, c)
+	m2 := NewExprTypedHeapReference("m2", "m2", tsStringMap, l, c)
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"keys", NewExprMapFunctionMust(MFKeys, []Expression{m1}, l, c), `(keys m1)`},
+		{"hasKey", NewExprMapFunctionMust(MFHasKey, []Expression{m1,
+			NewExprConstant(NewExprValueString("a"), l, c)}, l, c), `(hasKey m1 "a")`},
+		{"merge", NewExprMergeMust(m1, m2, MCFirst, l, c), `(merge first m1 m2)`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprMapFunction_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	tsIntegerMap := NewCompositeTypeSignature(VTMap, tsInteger)
+	intMap := func(m map[string]Value) Value {
+		return NewExprValueMap(tsInteger, m)
+	}
+	constant := func(v Value) Expression {
+		return NewExprConstant(v, l, c)
+	}
+	str := func(s string) Expression {
+		return NewExprConstant(NewExprValueString(s), l, c)
+	}
+	tsAnyMap := NewCompositeTypeSignature(VTMap, NewScalarTypeSignature(VTAny))
+	entry := func(key string, value Value) Value {
+		return NewExprValueMap(NewScalarTypeSignature(VTAny), map[string]Value{
+			"key":   NewExprValueAny(NewExprValueString(key)),
+			"value": NewExprValueAny(value),
+		})
+	}
+	m := constant(intMap(map[string]Value{
+		"b": NewExprValueInteger(2),
+		"a": NewExprValueInteger(1),
+		"c": EvNilInteger,
+	}))
+	tests := []struct {
+		name   string
+		mf     MapFunction
+		args   []Expression
+		result Value
+	}{
+		{"keys", MFKeys, []Expression{m}, NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+			NewExprValueString("a"), NewExprValueString("b"), NewExprValueString("c")})},
+		{"keysEmpty", MFKeys, []Expression{constant(intMap(map[string]Value{}))},
+			NewExprValueList(NewScalarTypeSignature(VTString), []Value{})},
+		{"values", MFValues, []Expression{m}, NewExprValueList(tsInteger, []Value{
+			NewExprValueInteger(1), NewExprValueInteger(2), EvNilInteger})},
+		{"entries", MFEntries, []Expression{m}, NewExprValueList(tsAnyMap, []Value{
+			entry("a", NewExprValueInteger(1)), entry("b", NewExprValueInteger(2)), entry("c", EvNilInteger)})},
+		{"entriesEmpty", MFEntries, []Expression{constant(intMap(map[string]Value{}))},
+			NewExprValueList(tsAnyMap, []Value{})},
+		{"hasKey", MFHasKey, []Expression{m, str("a")}, EvBooleanTrue},
+		{"hasKeyNilValue", MFHasKey, []Expression{m, str("c")}, EvBooleanTrue},
+		{"hasKeyFalse", MFHasKey, []Expression{m, str("d")}, EvBooleanFalse},
+		{"delete", MFDelete, []Expression{m, str("b")}, intMap(map[string]Value{
+			"a": NewExprValueInteger(1), "c": EvNilInteger})},
+		{"deleteMissing", MFDelete, []Expression{m, str("d")}, intMap(map[string]Value{
+			"a": NewExprValueInteger(1), "b": NewExprValueInteger(2), "c": EvNilInteger})},
+		// nil ---------------------------------------
+		{"keysNil", MFKeys, []Expression{constant(NewNilExprValue(tsIntegerMap))},
+			NewNilExprValue(NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString)))},
+		{"hasKeyNil", MFHasKey, []Expression{m, constant(EvNilString)}, EvNilBoolean},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, err := NewExprMapFunction(test.mf, test.args, l, c)
+			if err != nil {
+				t.Errorf("unexpected error creating expression: %v", err)
+				return
+			}
+			if !op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", op.ResultType(), test.result.Type)
+			}
+			res, err := op.Evaluate(newEmptyTestRequestContext())
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestExprMerge_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	strMap := func(m map[string]string) Value {
+		values := make(map[string]Value, len(m))
+		for k, v := range m {
+			values[k] = NewExprValueString(v)
+		}
+		return NewExprValueMap(NewScalarTypeSignature(VTString), values)
+	}
+	first := NewExprConstant(strMap(map[string]string{"a": "a1", "b": "b1"}), l, c)
+	last := NewExprConstant(strMap(map[string]string{"b": "b2", "c": "c2"}), l, c)
+	tests := []struct {
+		name     string
+		conflict MergeConflict
+		result   Value
+		err      bool
+	}{
+		{"last", MCLast, strMap(map[string]string{"a": "a1", "b": "b2", "c": "c2"}), false},
+		{"first", MCFirst, strMap(map[string]string{"a": "a1", "b": "b1", "c": "c2"}), false},
+		{"error", MCError, Value{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op := NewExprMergeMust(first, last, test.conflict, l, c)
+			res, err := op.Evaluate(newEmptyTestRequestContext())
+			if test.err {
+				if err == nil {
+					t.Errorf("expected evaluation error")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestExprMerge_EvaluateConflictKey(t *testing.T) {
+	l, c := 1, 2
+	values := make(map[string]Value)
+	for _, k := range []string{"k", "e", "x", "b", "q", "m", "z", "d"} {
+		values[k] = NewExprValueInteger(1)
+	}
+	m := NewExprConstant(NewExprValueMap(NewScalarTypeSignature(VTInteger), values), l, c)
+	op := NewExprMergeMust(m, m, MCError, l, c)
+	// All keys conflict. The first conflicting key in key order is reported.
+	for i := 0; i < 10; i++ {
+		_, err := op.Evaluate(newEmptyTestRequestContext())
+		if err == nil {
+			t.Errorf("expected evaluation error")
+			return
+		}
+		if !strings.Contains(err.Error(), "merge conflict for key b") {
+			t.Errorf("wrong evaluation error.\nactual:   %v\nexpected: merge conflict for key b", err)
+			return
+		}
+	}
+}
+
+func TestNewExprMapFunction_Error(t *testing.T) {
+	l, c := 1, 2
+	strs := NewExprConstant(NewExprValueMap(NewScalarTypeSignature(VTString), map[string]Value{}), l, c)
+	ints := NewExprConstant(NewExprValueMap(NewScalarTypeSignature(VTInteger), map[string]Value{}), l, c)
+	_, err := NewExprMapFunction(MapFunction("unknown"), []Expression{strs}, l, c)
+	if err == nil {
+		t.Errorf("expected error for unknown map function")
+	}
+	_, err = NewExprMapFunction(MFKeys, []Expression{NewExprConstant(NewExprValueString("foo"), l, c)}, l, c)
+	if err == nil {
+		t.Errorf("expected error for non-map argument")
+	}
+	_, err = NewExprMapFunction(MFHasKey, []Expression{strs}, l, c)
+	if err == nil {
+		t.Errorf("expected error for wrong argument count")
+	}
+	_, err = NewExprMapFunction(MFMerge, []Expression{strs, strs}, l, c)
+	if err == nil {
+		t.Errorf("expected error for merge")
+	}
+	_, err = NewExprMerge(strs, ints, MCLast, l, c)
+	if err == nil {
+		t.Errorf("expected error for different map types")
+	}
+	_, err = NewExprMerge(strs, strs, MergeConflict("unknown"), l, c)
+	if err == nil {
+		t.Errorf("expected error for unknown merge conflict resolution")
+	}
+}
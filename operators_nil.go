@@ -0,0 +1,123 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Nil operator type
+type NilOperator string
+
+const (
+	NOCoalesce NilOperator = "??"
+	NODefault  NilOperator = "default"
+	NOIsNil    NilOperator = "isNil"
+)
+
+// exprNil applies a nil handling operator to a set of operands. The operands and the result of the Expression are
+// specific to the operator.
+// ?? <value> <value> ... => the first operand not evaluating to nil (nil if all operands evaluate to nil)
+// default <value> <default value> => value if not nil, otherwise the default value
+// isNil <value> => boolean (true if the value is nil, never nil)
+// The operands of ?? and default must have the same result type. Note that the operands are evaluated lazily. That
+// is an operand is only evaluated if all operands before it evaluated to nil.
+type exprNil struct {
+	baseExpression
+	no  NilOperator
+	ops []Expression
+}
+
+func (op *exprNil) Evaluate(recCtx RequestContext) (Value, error) {
+	switch op.no {
+	case NOCoalesce, NODefault:
+		for _, subOp := range op.ops {
+			value, err := subOp.Evaluate(recCtx)
+			if err != nil {
+				return op.nilResult(), err
+			}
+			if !value.Nil() {
+				return value, nil
+			}
+		}
+		return op.nilResult(), nil
+	case NOIsNil:
+		value, err := op.ops[0].Evaluate(recCtx)
+		if err != nil {
+			return EvNilBoolean, err
+		}
+		return NewExprValueBoolean(value.Nil()), nil
+	default:
+		panic(fmt.Sprintf("unknown nil operator %v", op.no))
+	}
+}
+
+func (op *exprNil) String() string {
+	if op.no != NOCoalesce {
+		return functionString(string(op.no), op.ops)
+	}
+	operands := make([]string, 0, len(op.ops))
+	for _, subOp := range op.ops {
+		operands = append(operands, subOp.String())
+	}
+	return "(" + strings.Join(operands, " ?? ") + ")"
+}
+
+func (op *exprNil) Children() []Expression {
+	return append([]Expression(nil), op.ops...)
+}
+
+func (op *exprNil) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.ops))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.ops = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
+// NewExprNil creates a nil operator Expression. An error is returned if the nil operator is unknown, if the
+// number of operands is wrong or if the operands of ?? or default have different result types.
+func NewExprNil(no NilOperator, ops []Expression, line, col int) (Expression, error) {
+	var rt TypeSignature
+	switch no {
+	case NOCoalesce:
+		if len(ops) < 2 {
+			return nil, fmt.Errorf("%s expects at least 2 operands (got %d)", no, len(ops))
+		}
+		ut, err := unitType(string(no), ops)
+		if err != nil {
+			return nil, err
+		}
+		rt = ut
+	case NODefault:
+		if len(ops) != 2 {
+			return nil, fmt.Errorf("%s expects 2 operands (got %d)", no, len(ops))
+		}
+		ut, err := unitType(string(no), ops)
+		if err != nil {
+			return nil, err
+		}
+		rt = ut
+	case NOIsNil:
+		if len(ops) != 1 {
+			return nil, fmt.Errorf("%s expects 1 operand (got %d)", no, len(ops))
+		}
+		rt = NewScalarTypeSignature(VTBoolean)
+	default:
+		return nil, fmt.Errorf("unknown nil operator %v", no)
+	}
+	return &exprNil{
+		baseExpression: newBaseExpression(rt, line, col),
+		no:             no,
+		ops:            ops,
+	}, nil
+}
+
+func NewExprNilMust(no NilOperator, ops []Expression, line, col int) Expression {
+	expr, err := NewExprNil(no, ops, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating nil expression: %v", err))
+	}
+	return expr
+}
@@ -0,0 +1,111 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func TestExprNil_String(t *testing.T) {
+	l, c := 1, 2
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"coalesce", NewExprNilMust(NOCoalesce, []Expression{
+			NewExprHeapReference("a", "a", l, c),
+			NewExprHeapReference("b", "b", l, c),
+			NewExprConstant(NewExprValueString("c"), l, c)}, l, c),
+			`(a ?? b ?? "c")`},
+		{"default", NewExprNilMust(NODefault, []Expression{
+			NewExprHeapReference("a", "a", l, c),
+			NewExprConstant(NewExprValueString("b"), l, c)}, l, c),
+			`(default a "b")`},
+		{"isNil", NewExprNilMust(NOIsNil, []Expression{NewExprHeapReference("a", "a", l, c)}, l, c),
+			`(isNil a)`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprNil_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	reqCtx := newTestRequestContext(map[string]string{"a": "foo", "n": "5"})
+	ref := func(key string) Expression {
+		return NewExprHeapReference(key, key, l, c)
+	}
+	str := func(s string) Expression {
+		return NewExprConstant(NewExprValueString(s), l, c)
+	}
+	tests := []struct {
+		name   string
+		no     NilOperator
+		ops    []Expression
+		result Value
+	}{
+		{"coalesceFirst", NOCoalesce, []Expression{ref("a"), str("bar")}, NewExprValueString("foo")},
+		{"coalesceSecond", NOCoalesce, []Expression{ref("missing"), str("bar")}, NewExprValueString("bar")},
+		{"coalesceThird", NOCoalesce, []Expression{ref("missing"), ref("missing2"), ref("a")},
+			NewExprValueString("foo")},
+		{"coalesceAllNil", NOCoalesce, []Expression{ref("missing"), ref("missing2")}, EvNilString},
+		{"coalesceAdaptReference", NOCoalesce, []Expression{ref("n"), NewExprConstant(NewExprValueInteger(0), l, c)},
+			NewExprValueInteger(5)},
+		{"defaultValue", NODefault, []Expression{ref("a"), str("bar")}, NewExprValueString("foo")},
+		{"defaultNil", NODefault, []Expression{ref("missing"), str("bar")}, NewExprValueString("bar")},
+		{"isNilTrue", NOIsNil, []Expression{ref("missing")}, EvBooleanTrue},
+		{"isNilFalse", NOIsNil, []Expression{ref("a")}, EvBooleanFalse},
+		{"isNilList", NOIsNil, []Expression{
+			NewExprConstant(NewNilExprValue(NewCompositeTypeSignature(VTList, TsDefault)), l, c)}, EvBooleanTrue},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, err := NewExprNil(test.no, test.ops, l, c)
+			if err != nil {
+				t.Errorf("unexpected error creating expression: %v", err)
+				return
+			}
+			if !op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", op.ResultType(), test.result.Type)
+			}
+			res, err := op.Evaluate(reqCtx)
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestNewExprNil_Error(t *testing.T) {
+	l, c := 1, 2
+	str := NewExprConstant(NewExprValueString("foo"), l, c)
+	integer := NewExprConstant(NewExprValueInteger(1), l, c)
+	tests := []struct {
+		name string
+		no   NilOperator
+		ops  []Expression
+	}{
+		{"unknown", NilOperator("unknown"), []Expression{str}},
+		{"coalesceOneOperand", NOCoalesce, []Expression{str}},
+		{"coalesceTypes", NOCoalesce, []Expression{str, integer}},
+		{"defaultThreeOperands", NODefault, []Expression{str, str, str}},
+		{"defaultTypes", NODefault, []Expression{str, integer}},
+		{"isNilTwoOperands", NOIsNil, []Expression{str, str}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprNil(test.no, test.ops, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
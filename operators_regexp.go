@@ -0,0 +1,160 @@
+package goexpr
+
+import (
+	"fmt"
+)
+
+// Regexp function type
+type RegexpFunction string
+
+const (
+	RFFind    RegexpFunction = "regexpFind"
+	RFFindAll RegexpFunction = "regexpFindAll"
+	RFGroups  RegexpFunction = "regexpGroups"
+	RFReplace RegexpFunction = "regexpReplace"
+)
+
+// regexpFunctionSignature returns the parameter types and the result type for a regexp function.
+// If the regexp function is unknown false is returned.
+func regexpFunctionSignature(rf RegexpFunction) ([]TypeSignature, TypeSignature, bool) {
+	tsString := NewScalarTypeSignature(VTString)
+	tsRegexp := NewScalarTypeSignature(VTRegexp)
+	switch rf {
+	case RFFind:
+		return []TypeSignature{tsString, tsRegexp}, tsString, true
+	case RFFindAll:
+		return []TypeSignature{tsString, tsRegexp}, NewCompositeTypeSignature(VTList, tsString), true
+	case RFGroups:
+		return []TypeSignature{tsString, tsRegexp}, NewCompositeTypeSignature(VTMap, tsString), true
+	case RFReplace:
+		return []TypeSignature{tsString, tsRegexp, tsString}, tsString, true
+	}
+	return nil, TypeSignature{}, false
+}
+
+// exprRegexp applies a regexp function to a set of arguments. The arguments and the result of the Expression are
+// specific to the regexp function.
+// regexpFind <string> <regexp> => string (the leftmost match, nil if no match)
+// regexpFindAll <string> <regexp> => list of strings (all successive non-overlapping matches)
+// regexpGroups <string> <regexp> => map of strings (the named capture groups of the leftmost match, nil if no
+// match). A named group not participating in the match has a nil value.
+// regexpReplace <string> <regexp> <replacement> => string (all matches replaced by the replacement where $1 or
+// ${name} is replaced by the text of the corresponding capture group)
+// The pre-compiled regexp of the regexp value is used.
+// If one of the arguments evaluates to nil then nil is returned. That is the regexp Expression propagates nil.
+type exprRegexp struct {
+	baseExpression
+	rf   RegexpFunction
+	args []Expression
+}
+
+func (op *exprRegexp) Evaluate(recCtx RequestContext) (Value, error) {
+	args := make([]Value, 0, len(op.args))
+	for _, argOp := range op.args {
+		arg, err := argOp.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		// Regexp functions propagates nil
+		if arg.Nil() {
+			return op.nilResult(), nil
+		}
+		args = append(args, arg)
+	}
+
+	str := args[0].Value.(string)
+	re := args[1].Regexp
+	switch op.rf {
+	case RFFind:
+		loc := re.FindStringIndex(str)
+		if loc == nil {
+			return op.nilResult(), nil
+		}
+		return NewExprValueString(str[loc[0]:loc[1]]), nil
+	case RFFindAll:
+		matches := re.FindAllString(str, -1)
+		list := make([]Value, 0, len(matches))
+		for _, match := range matches {
+			list = append(list, NewExprValueString(match))
+		}
+		return NewExprValueList(NewScalarTypeSignature(VTString), list), nil
+	case RFGroups:
+		loc := re.FindStringSubmatchIndex(str)
+		if loc == nil {
+			return op.nilResult(), nil
+		}
+		groups := make(map[string]Value)
+		for i, name := range re.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			if loc[2*i] < 0 {
+				groups[name] = EvNilString
+				continue
+			}
+			groups[name] = NewExprValueString(str[loc[2*i]:loc[2*i+1]])
+		}
+		return NewExprValueMap(NewScalarTypeSignature(VTString), groups), nil
+	case RFReplace:
+		return NewExprValueString(re.ReplaceAllString(str, args[2].Value.(string))), nil
+	default:
+		panic(fmt.Sprintf("unknown regexp function %v", op.rf))
+	}
+}
+
+func (op *exprRegexp) String() string {
+	return functionString(string(op.rf), op.args)
+}
+
+func (op *exprRegexp) Children() []Expression {
+	return append([]Expression(nil), op.args...)
+}
+
+func (op *exprRegexp) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.args))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.args = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
+// NewExprRegexp creates a regexp function Expression. If the regexp argument is a constant string it is converted
+// to a constant regexp. An error is returned if the regexp function is unknown, if the arguments don't match the
+// parameters of the regexp function or if a constant regexp can't be compiled.
+func NewExprRegexp(rf RegexpFunction, args []Expression, line, col int) (Expression, error) {
+	params, rt, ok := regexpFunctionSignature(rf)
+	if !ok {
+		return nil, fmt.Errorf("unknown regexp function %v", rf)
+	}
+	if len(args) > 1 {
+		constant, ok := args[1].(*exprConstant)
+		if ok && constant.ResultType().IsValueType(VTString) && !constant.c.Nil() {
+			str := constant.c.Value.(string)
+			regexp, err := NewExprValueRegexp(str)
+			if err != nil {
+				return nil, fmt.Errorf("can't create regexp from %s: %v", str, err)
+			}
+			args = append([]Expression(nil), args...)
+			args[1] = NewExprConstant(regexp, constant.Line(), constant.Col())
+		}
+	}
+	err := checkArguments(string(rf), args, params)
+	if err != nil {
+		return nil, err
+	}
+	return &exprRegexp{
+		baseExpression: newBaseExpression(rt, line, col),
+		rf:             rf,
+		args:           args,
+	}, nil
+}
+
+func NewExprRegexpMust(rf RegexpFunction, args []Expression, line, col int) Expression {
+	expr, err := NewExprRegexp(rf, args, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating regexp expression: %v", err))
+	}
+	return expr
+}
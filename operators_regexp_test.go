@@ -0,0 +1,128 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func TestExprRegexp_String(t *testing.T) {
+	l, c := 1, 2
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"find", NewExprRegexpMust(RFFind, []Expression{
+			NewExprHeapReference("name", "name", l, c),
+			NewExprConstant(NewExprValueString("o+"), l, c)}, l, c),
+			`(regexpFind name "o+")`},
+		{"replace", NewExprRegexpMust(RFReplace, []Expression{
+			NewExprConstant(NewExprValueString("foo"), l, c),
+			NewExprConstant(NewExprValueString("o"), l, c),
+			NewExprConstant(NewExprValueString("0"), l, c)}, l, c),
+			`(regexpReplace "foo" "o" "0")`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprRegexp_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	reqCtx := newTestRequestContext(map[string]string{"name": "foo-123"})
+	str := func(s string) Expression {
+		return NewExprConstant(NewExprValueString(s), l, c)
+	}
+	strList := func(strs ...string) Value {
+		list := make([]Value, 0, len(strs))
+		for _, s := range strs {
+			list = append(list, NewExprValueString(s))
+		}
+		return NewExprValueList(NewScalarTypeSignature(VTString), list)
+	}
+	strMap := func(m map[string]Value) Value {
+		return NewExprValueMap(NewScalarTypeSignature(VTString), m)
+	}
+	tsStringList := NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString))
+	tsStringMap := NewCompositeTypeSignature(VTMap, NewScalarTypeSignature(VTString))
+	tests := []struct {
+		name   string
+		rf     RegexpFunction
+		args   []Expression
+		result Value
+	}{
+		{"find", RFFind, []Expression{str("foo 12 bar 345"), str(`\d+`)}, NewExprValueString("12")},
+		{"findNoMatch", RFFind, []Expression{str("foo"), str(`\d+`)}, EvNilString},
+		{"findReference", RFFind, []Expression{NewExprHeapReference("name", "name", l, c), str(`\d+`)},
+			NewExprValueString("123")},
+		{"findRegexpConstant", RFFind, []Expression{str("foo"), NewExprConstant(NewExprValueRegexpMust("o+"), l, c)},
+			NewExprValueString("oo")},
+		{"findAll", RFFindAll, []Expression{str("foo 12 bar 345"), str(`\d+`)}, strList("12", "345")},
+		{"findAllNoMatch", RFFindAll, []Expression{str("foo"), str(`\d+`)}, strList()},
+		{"groups", RFGroups, []Expression{str("2021-03"), str(`(?P<year>\d{4})-(?P<month>\d{2})(-(?P<day>\d{2}))?`)},
+			strMap(map[string]Value{
+				"year":  NewExprValueString("2021"),
+				"month": NewExprValueString("03"),
+				"day":   EvNilString,
+			})},
+		{"groupsUnnamed", RFGroups, []Expression{str("foo"), str(`(f)(o+)`)}, strMap(map[string]Value{})},
+		{"groupsNoMatch", RFGroups, []Expression{str("foo"), str(`(?P<n>\d+)`)}, NewNilExprValue(tsStringMap)},
+		{"replace", RFReplace, []Expression{str("foo 12 bar 345"), str(`\d+`), str("#")},
+			NewExprValueString("foo # bar #")},
+		{"replaceGroups", RFReplace, []Expression{str("foo-123"), str(`(?P<word>\w+)-(\d+)`), str("$2-${word}")},
+			NewExprValueString("123-foo")},
+		// nil ---------------------------------------
+		{"findNil", RFFind, []Expression{NewExprConstant(EvNilString, l, c), str("o")}, EvNilString},
+		{"findAllNilReference", RFFindAll, []Expression{NewExprHeapReference("missing", "missing", l, c), str("o")},
+			NewNilExprValue(tsStringList)},
+		{"replaceNil", RFReplace, []Expression{str("foo"), str("o"), NewExprConstant(EvNilString, l, c)},
+			EvNilString},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, err := NewExprRegexp(test.rf, test.args, l, c)
+			if err != nil {
+				t.Errorf("unexpected error creating expression: %v", err)
+				return
+			}
+			if !op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", op.ResultType(), test.result.Type)
+			}
+			res, err := op.Evaluate(reqCtx)
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestNewExprRegexp_Error(t *testing.T) {
+	l, c := 1, 2
+	str := NewExprConstant(NewExprValueString("foo"), l, c)
+	tests := []struct {
+		name string
+		rf   RegexpFunction
+		args []Expression
+	}{
+		{"unknown", RegexpFunction("unknown"), []Expression{str, str}},
+		{"argumentCount", RFReplace, []Expression{str, str}},
+		{"argumentType", RFFind, []Expression{str, NewExprConstant(NewExprValueInteger(1), l, c)}},
+		{"invalidRegexp", RFFind, []Expression{str, NewExprConstant(NewExprValueString("(foo"), l, c)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprRegexp(test.rf, test.args, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
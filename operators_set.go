@@ -0,0 +1,384 @@
+package goexpr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// exprSort sorts a list (the result of the list Expression). The result is a list of the same type as the list.
+// If a key Expression is specified the list values are sorted by the result of the key Expression. As for
+// exprLambda the reference specified by the heap key is set to the current list value before the key Expression
+// is evaluated. Otherwise the list values themselves are sorted.
+// The values (or keys) are ordered using Value.Compare() where nil is less than any non-nil value. The sort is
+// stable, that is equal values keep their original order (also when sorting in descending order).
+// If the list is nil then nil is returned. That is the sort Expression propagates nil.
+type exprSort struct {
+	baseExpression
+	opList Expression
+	// The Expression returning the sort key for a list value (may be nil)
+	opKey Expression
+	// The reference heap key where to store the current value of the list (only used with a key Expression)
+	key  string
+	desc bool
+}
+
+func (op *exprSort) Evaluate(recCtx RequestContext) (Value, error) {
+	list, err := op.opList.Evaluate(recCtx)
+	if err != nil {
+		return op.nilResult(), err
+	}
+	if list.Nil() {
+		return op.nilResult(), nil
+	}
+
+	values := append([]Value(nil), list.Value.([]Value)...)
+	sortKeys := values
+	if op.opKey != nil {
+		sortKeys = make([]Value, 0, len(values))
+		for _, value := range values {
+			err := recCtx.Assign(op.key, value)
+			if err != nil {
+				return op.nilResult(), err
+			}
+			sortKey, err := op.opKey.Evaluate(recCtx)
+			if err != nil {
+				return op.nilResult(), err
+			}
+			sortKeys = append(sortKeys, sortKey)
+		}
+	}
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		cmp := compareNilFirst(sortKeys[indexes[i]], sortKeys[indexes[j]])
+		if op.desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	sorted := make([]Value, 0, len(values))
+	for _, i := range indexes {
+		sorted = append(sorted, values[i])
+	}
+	return NewExprValueList(*op.ResultType().UnitType, sorted), nil
+}
+
+// compareNilFirst compares two comparable values of the same type where nil is less than any non-nil value
+func compareNilFirst(v1, v2 Value) int {
+	switch {
+	case v1.Nil() && v2.Nil():
+		return 0
+	case v1.Nil():
+		return -1
+	case v2.Nil():
+		return 1
+	}
+	return compareValues(v1, v2, nil)
+}
+
+// sortPrefix returns the string representation of the sort Expression up to the list (e.g. "sort desc x in ")
+func (op *exprSort) sortPrefix() string {
+	var sb strings.Builder
+	sb.WriteString("sort ")
+	if op.desc {
+		sb.WriteString("desc ")
+	}
+	if op.opKey != nil {
+		sb.WriteString(op.key)
+		sb.WriteString(" in ")
+	}
+	return sb.String()
+}
+
+func (op *exprSort) String() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString(op.sortPrefix())
+	sb.WriteString(op.opList.String())
+	if op.opKey != nil {
+		sb.WriteString(" by ")
+		sb.WriteString(op.opKey.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func (op *exprSort) Children() []Expression {
+	if op.opKey != nil {
+		return []Expression{op.opList, op.opKey}
+	}
+	return []Expression{op.opList}
+}
+
+func (op *exprSort) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.Children()))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.opList = children[0]
+	if op.opKey != nil {
+		cp.opKey = children[1]
+	}
+	return &cp, nil
+}
+
+// NewExprSort creates a sort Expression. The key Expression is optional (specify nil to sort the list values).
+// An error is returned if the list Expression doesn't return a list or if the list values (or the keys) are not
+// comparable.
+func NewExprSort(opList, opKey Expression, key string, desc bool, line, col int) (Expression, error) {
+	if !opList.ResultType().IsValueType(VTList) {
+		return nil, fmt.Errorf("sort expects a list (got %v)", opList.ResultType())
+	}
+	if opKey == nil {
+		unit := *opList.ResultType().UnitType
+		if !VTMetadata.ComparableType(unit) {
+			return nil, fmt.Errorf("sort expects a list of comparable values (got %v)", unit)
+		}
+	} else if !VTMetadata.ComparableType(opKey.ResultType()) {
+		return nil, fmt.Errorf("sort expects a comparable key (got %v)", opKey.ResultType())
+	}
+	return &exprSort{
+		baseExpression: newBaseExpression(opList.ResultType(), line, col),
+		opList:         opList,
+		opKey:          opKey,
+		key:            key,
+		desc:           desc,
+	}, nil
+}
+
+func NewExprSortMust(opList, opKey Expression, key string, desc bool, line, col int) Expression {
+	expr, err := NewExprSort(opList, opKey, key, desc, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating sort expression: %v", err))
+	}
+	return expr
+}
+
+// Set operation type
+type SetOperation string
+
+const (
+	SOContainsAll SetOperation = "containsAll"
+	SOContainsAny SetOperation = "containsAny"
+	SODifference  SetOperation = "difference"
+	SOIntersect   SetOperation = "intersect"
+	SOUnion       SetOperation = "union"
+	SOUnique      SetOperation = "unique"
+)
+
+// exprSet applies a set operation to one or two lists. The lists are treated as sets where the values are compared
+// using Value.Equal(). Lists returned keep the order of the values in the (first) list.
+// unique <list> => list (the list without duplicate values)
+// union <list1> <list2> => list (the unique values in list1 followed by the unique values in list2 not in list1)
+// intersect <list1> <list2> => list (the unique values in list1 also in list2)
+// difference <list1> <list2> => list (the unique values in list1 not in list2)
+// containsAll <list1> <list2> => boolean (true if all values in list2 are in list1, true if list2 is empty)
+// containsAny <list1> <list2> => boolean (true if any value in list2 is in list1, false if list2 is empty)
+// The lists must be of the same type and a returned list has the same type as the lists.
+// If one of the lists is nil then nil is returned. That is the set Expression propagates nil.
+type exprSet struct {
+	baseExpression
+	so   SetOperation
+	args []Expression
+}
+
+func (op *exprSet) Evaluate(recCtx RequestContext) (Value, error) {
+	lists := make([][]Value, 0, len(op.args))
+	for _, argOp := range op.args {
+		arg, err := argOp.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		// Set operations propagates nil
+		if arg.Nil() {
+			return op.nilResult(), nil
+		}
+		lists = append(lists, arg.Value.([]Value))
+	}
+
+	var result []Value
+	switch op.so {
+	case SOContainsAll:
+		set := newValueSet(lists[0])
+		for _, value := range lists[1] {
+			if !set.contains(value) {
+				return EvBooleanFalse, nil
+			}
+		}
+		return EvBooleanTrue, nil
+	case SOContainsAny:
+		set := newValueSet(lists[0])
+		for _, value := range lists[1] {
+			if set.contains(value) {
+				return EvBooleanTrue, nil
+			}
+		}
+		return EvBooleanFalse, nil
+	case SODifference:
+		other := newValueSet(lists[1])
+		result = appendUnique(newValueSet(nil), nil, lists[0], func(value Value) bool {
+			return !other.contains(value)
+		})
+	case SOIntersect:
+		other := newValueSet(lists[1])
+		result = appendUnique(newValueSet(nil), nil, lists[0], other.contains)
+	case SOUnion:
+		seen := newValueSet(nil)
+		result = appendUnique(seen, nil, lists[0], nil)
+		result = appendUnique(seen, result, lists[1], nil)
+	case SOUnique:
+		result = appendUnique(newValueSet(nil), nil, lists[0], nil)
+	default:
+		panic(fmt.Sprintf("unknown set operation %v", op.so))
+	}
+	return NewExprValueList(*op.ResultType().UnitType, result), nil
+}
+
+// valueSet is a set of values where the values are compared using Value.Equal(). Non-nil scalar values are looked
+// up by a key (see setKey()). Other values (nil and composite values) are compared one by one.
+type valueSet struct {
+	keys   map[string]bool
+	others []Value
+}
+
+func newValueSet(values []Value) *valueSet {
+	set := &valueSet{keys: make(map[string]bool, len(values))}
+	for _, value := range values {
+		set.add(value)
+	}
+	return set
+}
+
+// add adds the value to the set. If the set already contains the value false is returned.
+func (vs *valueSet) add(value Value) bool {
+	if key, ok := setKey(value); ok {
+		if vs.keys[key] {
+			return false
+		}
+		vs.keys[key] = true
+		return true
+	}
+	if vs.contains(value) {
+		return false
+	}
+	vs.others = append(vs.others, value)
+	return true
+}
+
+// contains returns true if the set contains a value equal to the specified value
+func (vs *valueSet) contains(value Value) bool {
+	if key, ok := setKey(value); ok {
+		return vs.keys[key]
+	}
+	for _, v := range vs.others {
+		if v.Equal(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// setKey returns a key for a non-nil scalar value where two values have the same key if, and only if, they are
+// equal (see Value.Equal()). The key is the type followed by the string representation of the value. A decimal is
+// represented without trailing zeros as decimals with different scales may be equal. For other values false is
+// returned.
+func setKey(value Value) (string, bool) {
+	if value.Nil() || !value.Type.Scalar() {
+		return "", false
+	}
+	if value.Type.IsValueType(VTAny) {
+		key, ok := setKey(value.Value.(Value))
+		return "any " + key, ok
+	}
+	str := value.String()
+	if value.Type.IsValueType(VTDecimal) && strings.Contains(str, ".") {
+		str = strings.TrimSuffix(strings.TrimRight(str, "0"), ".")
+	}
+	return value.Type.Text() + " " + str, true
+}
+
+// appendUnique appends the values not already in the result to the result. Seen is the set of values in the result
+// and is updated with the appended values. If include is specified only values for which include returns true are
+// appended.
+func appendUnique(seen *valueSet, result []Value, values []Value, include func(Value) bool) []Value {
+	if result == nil {
+		result = make([]Value, 0, len(values))
+	}
+	for _, value := range values {
+		if include != nil && !include(value) {
+			continue
+		}
+		if seen.add(value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func (op *exprSet) String() string {
+	return functionString(string(op.so), op.args)
+}
+
+func (op *exprSet) Children() []Expression {
+	return append([]Expression(nil), op.args...)
+}
+
+func (op *exprSet) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.args))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.args = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
+// NewExprSet creates a set operation Expression. An error is returned if the set operation is unknown, if the
+// number of arguments is wrong or if the arguments are not lists of the same type.
+func NewExprSet(so SetOperation, args []Expression, line, col int) (Expression, error) {
+	var count int
+	switch so {
+	case SOUnique:
+		count = 1
+	case SOContainsAll, SOContainsAny, SODifference, SOIntersect, SOUnion:
+		count = 2
+	default:
+		return nil, fmt.Errorf("unknown set operation %v", so)
+	}
+	if len(args) != count {
+		return nil, fmt.Errorf("%s expects %d arguments (got %d)", so, count, len(args))
+	}
+	ts := args[0].ResultType()
+	if !ts.IsValueType(VTList) {
+		return nil, fmt.Errorf("argument 1 of %s must be a list (got %v)", so, ts)
+	}
+	params := make([]TypeSignature, count)
+	for i := range params {
+		params[i] = ts
+	}
+	err := checkArguments(string(so), args, params)
+	if err != nil {
+		return nil, err
+	}
+	rt := ts
+	if so == SOContainsAll || so == SOContainsAny {
+		rt = NewScalarTypeSignature(VTBoolean)
+	}
+	return &exprSet{
+		baseExpression: newBaseExpression(rt, line, col),
+		so:             so,
+		args:           args,
+	}, nil
+}
+
+func NewExprSetMust(so SetOperation, args []Expression, line, col int) Expression {
+	expr, err := NewExprSet(so, args, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating set expression: %v", err))
+	}
+	return expr
+}
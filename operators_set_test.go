@@ -0,0 +1,260 @@
+package goexpr
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExprSort_String(t *testing.T) {
+	l, c := 1, 2
+	list := NewExprTypedHeapReference("names", "names",
+		NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString)), l, c)
+	length := NewExprStringMust(SFLength, []Expression{NewExprHeapReference("x", "x", l, c)}, l, c)
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"asc", NewExprSortMust(list, nil, "", false, l, c), `(sort names)`},
+		{"desc", NewExprSortMust(list, nil, "", true, l, c), `(sort desc names)`},
+		{"key", NewExprSortMust(list, length, "x", false, l, c), `(sort x in names by (length x))`},
+		{"keyDesc", NewExprSortMust(list, length, "x", true, l, c), `(sort desc x in names by (length x))`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprSort_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	strList := func(strs ...string) Expression {
+		list := make([]Value, 0, len(strs))
+		for _, s := range strs {
+			list = append(list, NewExprValueString(s))
+		}
+		return NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), list), l, c)
+	}
+	intList := func(ints ...int) Expression {
+		list := make([]Value, 0, len(ints))
+		for _, i := range ints {
+			list = append(list, NewExprValueInteger(i))
+		}
+		return NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), list), l, c)
+	}
+	withNil := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{
+		NewExprValueInteger(2), EvNilInteger, NewExprValueInteger(1)}), l, c)
+	length := NewExprStringMust(SFLength, []Expression{NewExprHeapReference("x", "x", l, c)}, l, c)
+	tests := []struct {
+		name   string
+		op     Expression
+		result Expression
+	}{
+		{"strings", NewExprSortMust(strList("b", "c", "a"), nil, "", false, l, c), strList("a", "b", "c")},
+		{"stringsDesc", NewExprSortMust(strList("b", "c", "a"), nil, "", true, l, c), strList("c", "b", "a")},
+		{"integers", NewExprSortMust(intList(10, -1, 2), nil, "", false, l, c), intList(-1, 2, 10)},
+		{"integerExtremes", NewExprSortMust(intList(1, math.MinInt64, math.MaxInt64, -1), nil, "", false, l, c),
+			intList(math.MinInt64, -1, 1, math.MaxInt64)},
+		{"empty", NewExprSortMust(intList(), nil, "", false, l, c), intList()},
+		{"nilFirst", NewExprSortMust(withNil, nil, "", false, l, c),
+			NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{
+				EvNilInteger, NewExprValueInteger(1), NewExprValueInteger(2)}), l, c)},
+		{"key", NewExprSortMust(strList("ccc", "a", "bb"), length, "x", false, l, c), strList("a", "bb", "ccc")},
+		{"keyStable", NewExprSortMust(strList("bb", "a", "aa", "c"), length, "x", false, l, c),
+			strList("a", "c", "bb", "aa")},
+		{"keyStableDesc", NewExprSortMust(strList("bb", "a", "aa", "c"), length, "x", true, l, c),
+			strList("bb", "aa", "a", "c")},
+		{"nil", NewExprSortMust(NewExprConstant(NewNilExprValue(
+			NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString))), l, c), nil, "", false, l, c),
+			NewExprConstant(NewNilExprValue(
+				NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString))), l, c)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reqCtx := newEmptyTestRequestContext()
+			res, err := test.op.Evaluate(reqCtx)
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			expected, _ := test.result.Evaluate(reqCtx)
+			if !res.Equal(expected) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, expected)
+			}
+		})
+	}
+}
+
+func TestNewExprSort_Error(t *testing.T) {
+	l, c := 1, 2
+	str := NewExprConstant(NewExprValueString("foo"), l, c)
+	regexps := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTRegexp), []Value{}), l, c)
+	strs := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{}), l, c)
+	tests := []struct {
+		name string
+		list Expression
+		key  Expression
+	}{
+		{"notList", str, nil},
+		{"notComparable", regexps, nil},
+		{"keyNotComparable", strs, NewExprConstant(NewExprValueRegexpMust("foo"), l, c)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprSort(test.list, test.key, "x", false, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestExprSet_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	tsStringList := NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString))
+	strList := func(strs ...string) Value {
+		list := make([]Value, 0, len(strs))
+		for _, s := range strs {
+			list = append(list, NewExprValueString(s))
+		}
+		return NewExprValueList(NewScalarTypeSignature(VTString), list)
+	}
+	list := func(strs ...string) Expression {
+		return NewExprConstant(strList(strs...), l, c)
+	}
+	nilList := NewExprConstant(NewNilExprValue(tsStringList), l, c)
+	tests := []struct {
+		name   string
+		so     SetOperation
+		args   []Expression
+		result Value
+	}{
+		{"unique", SOUnique, []Expression{list("b", "a", "b", "c", "a")}, strList("b", "a", "c")},
+		{"uniqueEmpty", SOUnique, []Expression{list()}, strList()},
+		{"union", SOUnion, []Expression{list("a", "b", "a"), list("c", "b", "d")}, strList("a", "b", "c", "d")},
+		{"intersect", SOIntersect, []Expression{list("admin", "user", "guest", "user"), list("user", "admin")},
+			strList("admin", "user")},
+		{"intersectEmpty", SOIntersect, []Expression{list("a"), list("b")}, strList()},
+		{"difference", SODifference, []Expression{list("a", "b", "c", "a"), list("b")}, strList("a", "c")},
+		{"containsAll", SOContainsAll, []Expression{list("admin", "user"), list("user", "admin")}, EvBooleanTrue},
+		{"containsAllFalse", SOContainsAll, []Expression{list("user"), list("user", "admin")}, EvBooleanFalse},
+		{"containsAllEmpty", SOContainsAll, []Expression{list("user"), list()}, EvBooleanTrue},
+		{"containsAny", SOContainsAny, []Expression{list("user"), list("admin", "user")}, EvBooleanTrue},
+		{"containsAnyFalse", SOContainsAny, []Expression{list("guest"), list("admin", "user")}, EvBooleanFalse},
+		{"containsAnyEmpty", SOContainsAny, []Expression{list("user"), list()}, EvBooleanFalse},
+		// nil ---------------------------------------
+		{"uniqueNil", SOUnique, []Expression{nilList}, NewNilExprValue(tsStringList)},
+		{"unionNil", SOUnion, []Expression{list("a"), nilList}, NewNilExprValue(tsStringList)},
+		{"containsAllNil", SOContainsAll, []Expression{nilList, list("a")}, EvNilBoolean},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, err := NewExprSet(test.so, test.args, l, c)
+			if err != nil {
+				t.Errorf("unexpected error creating expression: %v", err)
+				return
+			}
+			if !op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", op.ResultType(), test.result.Type)
+			}
+			res, err := op.Evaluate(newEmptyTestRequestContext())
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestExprSet_EvaluateValueKinds(t *testing.T) {
+	l, c := 1, 2
+	tsDecimal := NewScalarTypeSignature(VTDecimal)
+	tsIntegerList := NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTInteger))
+	decList := func(strs ...string) Value {
+		list := make([]Value, 0, len(strs))
+		for _, s := range strs {
+			list = append(list, NewExprValueDecimal(ParseDecimalMust(s)))
+		}
+		return NewExprValueList(tsDecimal, list)
+	}
+	intList := func(ints ...int) Value {
+		list := make([]Value, 0, len(ints))
+		for _, i := range ints {
+			list = append(list, NewExprValueInteger(i))
+		}
+		return NewExprValueList(NewScalarTypeSignature(VTInteger), list)
+	}
+	listList := func(lists ...Value) Value {
+		return NewExprValueList(tsIntegerList, lists)
+	}
+	constant := func(v Value) Expression {
+		return NewExprConstant(v, l, c)
+	}
+	tests := []struct {
+		name   string
+		so     SetOperation
+		args   []Expression
+		result Value
+	}{
+		{"uniqueDecimalScale", SOUnique, []Expression{constant(decList("1.5", "1.50", "2", "2.0", "0.00", "0"))},
+			decList("1.5", "2", "0.00")},
+		{"intersectDecimalScale", SOIntersect, []Expression{constant(decList("10", "1.0")), constant(decList("1"))},
+			decList("1.0")},
+		{"uniqueNilDecimal", SOUnique, []Expression{constant(NewExprValueList(tsDecimal,
+			[]Value{NewNilExprValue(tsDecimal), NewExprValueDecimal(NewDecimalFromInt(0)), NewNilExprValue(tsDecimal)}))},
+			NewExprValueList(tsDecimal, []Value{NewNilExprValue(tsDecimal), NewExprValueDecimal(NewDecimalFromInt(0))})},
+		{"unionList", SOUnion, []Expression{constant(listList(intList(1, 2), intList(1))),
+			constant(listList(intList(1), intList(2, 1), intList(1, 2)))},
+			listList(intList(1, 2), intList(1), intList(2, 1))},
+		{"differenceList", SODifference, []Expression{constant(listList(intList(1, 2), intList(1))),
+			constant(listList(intList(1)))}, listList(intList(1, 2))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, err := NewExprSet(test.so, test.args, l, c)
+			if err != nil {
+				t.Errorf("unexpected error creating expression: %v", err)
+				return
+			}
+			res, err := op.Evaluate(newEmptyTestRequestContext())
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) || res.String() != test.result.String() {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestNewExprSet_Error(t *testing.T) {
+	l, c := 1, 2
+	strs := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{}), l, c)
+	ints := NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{}), l, c)
+	tests := []struct {
+		name string
+		so   SetOperation
+		args []Expression
+	}{
+		{"unknown", SetOperation("unknown"), []Expression{strs}},
+		{"argumentCount", SOUnion, []Expression{strs}},
+		{"notList", SOUnique, []Expression{NewExprConstant(NewExprValueString("foo"), l, c)}},
+		{"differentTypes", SOIntersect, []Expression{strs, ints}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprSet(test.so, test.args, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String function type
+type StringFunction string
+
+const (
+	SFContains  StringFunction = "contains"
+	SFHasPrefix StringFunction = "hasPrefix"
+	SFHasSuffix StringFunction = "hasSuffix"
+	SFJoin      StringFunction = "join"
+	SFLength    StringFunction = "length"
+	SFLower     StringFunction = "lower"
+	SFReplace   StringFunction = "replace"
+	SFSplit     StringFunction = "split"
+	SFSubstring StringFunction = "substring"
+	SFTrim      StringFunction = "trim"
+	SFUpper     StringFunction = "upper"
+)
+
+// stringFunctionSignature returns the parameter types and the result type for a string function.
+// If the string function is unknown false is returned.
+func stringFunctionSignature(sf StringFunction) ([]TypeSignature, TypeSignature, bool) {
+	tsString := NewScalarTypeSignature(VTString)
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	tsBoolean := NewScalarTypeSignature(VTBoolean)
+	tsStringList := NewCompositeTypeSignature(VTList, tsString)
+	switch sf {
+	case SFContains, SFHasPrefix, SFHasSuffix:
+		return []TypeSignature{tsString, tsString}, tsBoolean, true
+	case SFJoin:
+		return []TypeSignature{tsStringList, tsString}, tsString, true
+	case SFLength:
+		return []TypeSignature{tsString}, tsInteger, true
+	case SFLower, SFTrim, SFUpper:
+		return []TypeSignature{tsString}, tsString, true
+	case SFReplace:
+		return []TypeSignature{tsString, tsString, tsString}, tsString, true
+	case SFSplit:
+		return []TypeSignature{tsString, tsString}, tsStringList, true
+	case SFSubstring:
+		return []TypeSignature{tsString, tsInteger, tsInteger}, tsString, true
+	}
+	return nil, TypeSignature{}, false
+}
+
+// exprString applies a string function to a set of arguments. The arguments and the result of the
+// Expression are specific to the string function.
+// contains <string> <substring> => boolean
+// hasPrefix <string> <prefix> => boolean
+// hasSuffix <string> <suffix> => boolean
+// join <list of strings> <separator> => string
+// length <string> => integer (number of characters)
+// lower <string> => string
+// replace <string> <old> <new> => string (all occurrences of old replaced by new)
+// split <string> <separator> => list of strings
+// substring <string> <start> <end> => string (characters from start up to, but not including, end)
+// trim <string> => string (leading and trailing white space removed)
+// upper <string> => string
+// Substring indexes out of range are adjusted to the closest valid index.
+// If one of the arguments evaluates to nil then nil is returned. That is the string Expression propagates nil.
+type exprString struct {
+	baseExpression
+	sf   StringFunction
+	args []Expression
+}
+
+func (op *exprString) Evaluate(recCtx RequestContext) (Value, error) {
+	args := make([]Value, 0, len(op.args))
+	for _, argOp := range op.args {
+		arg, err := argOp.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		args = append(args, arg)
+	}
+	// String functions propagates nil
+	for _, arg := range args {
+		if arg.Nil() {
+			return op.nilResult(), nil
+		}
+	}
+
+	switch op.sf {
+	case SFContains:
+		return NewExprValueBoolean(strings.Contains(args[0].Value.(string), args[1].Value.(string))), nil
+	case SFHasPrefix:
+		return NewExprValueBoolean(strings.HasPrefix(args[0].Value.(string), args[1].Value.(string))), nil
+	case SFHasSuffix:
+		return NewExprValueBoolean(strings.HasSuffix(args[0].Value.(string), args[1].Value.(string))), nil
+	case SFJoin:
+		list := args[0].Value.([]Value)
+		strs := make([]string, 0, len(list))
+		for _, v := range list {
+			// Nil list values are joined as empty strings
+			if v.Nil() {
+				strs = append(strs, "")
+				continue
+			}
+			strs = append(strs, v.Value.(string))
+		}
+		return NewExprValueString(strings.Join(strs, args[1].Value.(string))), nil
+	case SFLength:
+		return NewExprValueInteger(len([]rune(args[0].Value.(string)))), nil
+	case SFLower:
+		return NewExprValueString(strings.ToLower(args[0].Value.(string))), nil
+	case SFReplace:
+		return NewExprValueString(strings.Replace(args[0].Value.(string), args[1].Value.(string),
+			args[2].Value.(string), -1)), nil
+	case SFSplit:
+		strs := strings.Split(args[0].Value.(string), args[1].Value.(string))
+		list := make([]Value, 0, len(strs))
+		for _, str := range strs {
+			list = append(list, NewExprValueString(str))
+		}
+		return NewExprValueList(NewScalarTypeSignature(VTString), list), nil
+	case SFSubstring:
+		runes := []rune(args[0].Value.(string))
+		start := clampIndex(args[1].Value.(int), len(runes))
+		end := clampIndex(args[2].Value.(int), len(runes))
+		if start >= end {
+			return EvStringEmpty, nil
+		}
+		return NewExprValueString(string(runes[start:end])), nil
+	case SFTrim:
+		return NewExprValueString(strings.TrimSpace(args[0].Value.(string))), nil
+	case SFUpper:
+		return NewExprValueString(strings.ToUpper(args[0].Value.(string))), nil
+	default:
+		panic(fmt.Sprintf("unknown string function %v", op.sf))
+	}
+}
+
+// clampIndex returns the index adjusted to the range [0, length].
+func clampIndex(index, length int) int {
+	if index < 0 {
+		return 0
+	}
+	if index > length {
+		return length
+	}
+	return index
+}
+
+func (op *exprString) String() string {
+	return functionString(string(op.sf), op.args)
+}
+
+func (op *exprString) Children() []Expression {
+	return append([]Expression(nil), op.args...)
+}
+
+func (op *exprString) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.args))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.args = append([]Expression(nil), children...)
+	return &cp, nil
+}
+
+// NewExprString creates a string function Expression. An error is returned if the string function is unknown or
+// if the arguments don't match the parameters of the string function.
+func NewExprString(sf StringFunction, args []Expression, line, col int) (Expression, error) {
+	params, rt, ok := stringFunctionSignature(sf)
+	if !ok {
+		return nil, fmt.Errorf("unknown string function %v", sf)
+	}
+	err := checkArguments(string(sf), args, params)
+	if err != nil {
+		return nil, err
+	}
+	return &exprString{
+		baseExpression: newBaseExpression(rt, line, col),
+		sf:             sf,
+		args:           args,
+	}, nil
+}
+
+func NewExprStringMust(sf StringFunction, args []Expression, line, col int) Expression {
+	expr, err := NewExprString(sf, args, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating string expression: %v", err))
+	}
+	return expr
+}
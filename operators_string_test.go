@@ -0,0 +1,124 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func TestExprString_String(t *testing.T) {
+	l, c := 1, 2
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"lower", NewExprStringMust(SFLower, []Expression{
+			NewExprHeapReference("name", "name", l, c)}, l, c),
+			`(lower name)`},
+		{"substring", NewExprStringMust(SFSubstring, []Expression{
+			NewExprConstant(NewExprValueString("foobar"), l, c),
+			NewExprConstant(NewExprValueInteger(0), l, c),
+			NewExprConstant(NewExprValueInteger(3), l, c)}, l, c),
+			`(substring "foobar" 0 3)`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprString_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	reqCtx := newTestRequestContext(map[string]string{"name": "Foo", "length": "2"})
+	str := func(s string) Expression {
+		return NewExprConstant(NewExprValueString(s), l, c)
+	}
+	integer := func(i int) Expression {
+		return NewExprConstant(NewExprValueInteger(i), l, c)
+	}
+	strList := func(strs ...string) Value {
+		list := make([]Value, 0, len(strs))
+		for _, s := range strs {
+			list = append(list, NewExprValueString(s))
+		}
+		return NewExprValueList(NewScalarTypeSignature(VTString), list)
+	}
+	tests := []struct {
+		name   string
+		sf     StringFunction
+		args   []Expression
+		result Value
+	}{
+		{"contains", SFContains, []Expression{str("foobar"), str("oba")}, EvBooleanTrue},
+		{"containsFalse", SFContains, []Expression{str("foobar"), str("baz")}, EvBooleanFalse},
+		{"hasPrefix", SFHasPrefix, []Expression{str("foobar"), str("foo")}, EvBooleanTrue},
+		{"hasSuffix", SFHasSuffix, []Expression{str("foobar"), str("foo")}, EvBooleanFalse},
+		{"join", SFJoin, []Expression{NewExprConstant(strList("a", "b", "c"), l, c), str(",")},
+			NewExprValueString("a,b,c")},
+		{"length", SFLength, []Expression{str("åäö")}, NewExprValueInteger(3)},
+		{"lower", SFLower, []Expression{str("FooBar")}, NewExprValueString("foobar")},
+		{"lowerReference", SFLower, []Expression{NewExprHeapReference("name", "name", l, c)},
+			NewExprValueString("foo")},
+		{"replace", SFReplace, []Expression{str("foo foo"), str("o"), str("0")}, NewExprValueString("f00 f00")},
+		{"split", SFSplit, []Expression{str("a,b,c"), str(",")}, strList("a", "b", "c")},
+		{"substring", SFSubstring, []Expression{str("foobar"), integer(1), integer(4)}, NewExprValueString("oob")},
+		{"substringReference", SFSubstring, []Expression{str("foobar"), integer(0),
+			NewExprHeapReference("length", "length", l, c)}, NewExprValueString("fo")},
+		{"substringOutOfRange", SFSubstring, []Expression{str("foobar"), integer(-2), integer(10)},
+			NewExprValueString("foobar")},
+		{"substringEmpty", SFSubstring, []Expression{str("foobar"), integer(4), integer(2)}, EvStringEmpty},
+		{"trim", SFTrim, []Expression{str("  foo \n")}, NewExprValueString("foo")},
+		{"upper", SFUpper, []Expression{str("FooBar")}, NewExprValueString("FOOBAR")},
+		// nil ---------------------------------------
+		{"lowerNil", SFLower, []Expression{NewExprConstant(EvNilString, l, c)}, EvNilString},
+		{"containsNil", SFContains, []Expression{str("foo"), NewExprConstant(EvNilString, l, c)}, EvNilBoolean},
+		{"lengthNilReference", SFLength, []Expression{NewExprHeapReference("missing", "missing", l, c)},
+			EvNilInteger},
+		{"splitNil", SFSplit, []Expression{NewExprConstant(EvNilString, l, c), str(",")},
+			NewNilExprValue(NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString)))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, err := NewExprString(test.sf, test.args, l, c)
+			if err != nil {
+				t.Errorf("unexpected error creating expression: %v", err)
+				return
+			}
+			if !op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", op.ResultType(), test.result.Type)
+			}
+			res, err := op.Evaluate(reqCtx)
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestNewExprString_Error(t *testing.T) {
+	l, c := 1, 2
+	tests := []struct {
+		name string
+		sf   StringFunction
+		args []Expression
+	}{
+		{"unknown", StringFunction("unknown"), []Expression{NewExprConstant(EvStringEmpty, l, c)}},
+		{"argumentCount", SFLower, []Expression{}},
+		{"argumentType", SFLower, []Expression{NewExprConstant(NewExprValueInteger(1), l, c)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprString(test.sf, test.args, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Switch arm type
+type ArmType string
+
+const (
+	ATEqual ArmType = "equal"
+	ATGuard ArmType = "guard"
+	ATMatch ArmType = "match"
+	ATRange ArmType = "range"
+)
+
+// SwitchArm holds an arm of a switch Expression. The arm is selected if its test succeeds. The test is specific to
+// the arm type.
+// Equal: the subject is equal to one of the values.
+// Match: the subject (must be a string) matches the regexp given by the single value.
+// Range: the subject is within the range given by the two values (low and high, both inclusive). A nil Expression
+// is an open bound.
+// Guard: the single value (must be a boolean) evaluates to true. The subject is not used.
+type SwitchArm struct {
+	Type   ArmType
+	Values []Expression
+	// The result of the switch Expression if the arm is selected
+	Result Expression
+}
+
+// exprSwitch evaluates a subject once and tests the result against a set of arms in order. The result of the
+// Expression is the result of the first arm whose test succeeds. If no arm is selected the result of the default
+// Expression is returned. If no default Expression is specified nil is returned.
+// If a heap key is specified the subject is assigned to the key before the arms are tested. This makes it possible
+// to reference the subject from guards and results.
+// If the subject evaluates to nil only equality arms (having a nil value) and guards may be selected. A guard
+// evaluating to nil is not selected.
+type exprSwitch struct {
+	baseExpression
+	// The subject tested by the arms (nil if only guard arms are used)
+	opSubject Expression
+	arms      []SwitchArm
+	// The result if no arm is selected (may be nil)
+	opDefault Expression
+	// The reference heap key where to store the subject (may be empty)
+	key string
+}
+
+func (op *exprSwitch) Evaluate(recCtx RequestContext) (Value, error) {
+	var subject Value
+	if op.opSubject != nil {
+		var err error
+		subject, err = op.opSubject.Evaluate(recCtx)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		if op.key != "" {
+			err := recCtx.Assign(op.key, subject)
+			if err != nil {
+				return op.nilResult(), err
+			}
+		}
+	}
+
+	for _, arm := range op.arms {
+		selected, err := op.test(recCtx, arm, subject)
+		if err != nil {
+			return op.nilResult(), err
+		}
+		if selected {
+			return arm.Result.Evaluate(recCtx)
+		}
+	}
+	if op.opDefault == nil {
+		return op.nilResult(), nil
+	}
+	return op.opDefault.Evaluate(recCtx)
+}
+
+// test returns true if the arm is selected for the subject
+func (op *exprSwitch) test(recCtx RequestContext, arm SwitchArm, subject Value) (bool, error) {
+	switch arm.Type {
+	case ATEqual:
+		for _, valueOp := range arm.Values {
+			value, err := valueOp.Evaluate(recCtx)
+			if err != nil {
+				return false, err
+			}
+			if subject.Equal(value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case ATGuard:
+		guard, err := arm.Values[0].Evaluate(recCtx)
+		if err != nil {
+			return false, err
+		}
+		return !guard.Nil() && guard.Value.(bool), nil
+	case ATMatch:
+		if subject.Nil() {
+			return false, nil
+		}
+		matcher, err := arm.Values[0].Evaluate(recCtx)
+		if err != nil {
+			return false, err
+		}
+		if matcher.Nil() {
+			return false, nil
+		}
+		return matcher.Regexp.MatchString(subject.Value.(string)), nil
+	case ATRange:
+		if subject.Nil() {
+			return false, nil
+		}
+		for i, boundOp := range arm.Values {
+			if boundOp == nil {
+				continue
+			}
+			bound, err := boundOp.Evaluate(recCtx)
+			if err != nil {
+				return false, err
+			}
+			if bound.Nil() {
+				return false, nil
+			}
+			cmp := subject.Compare(bound).Value.(int)
+			if (i == 0 && cmp < 0) || (i == 1 && cmp > 0) {
+				return false, nil
+			}
+		}
+		return true, nil
+	default:
+		panic(fmt.Sprintf("unknown switch arm type %v", arm.Type))
+	}
+}
+
+func (op *exprSwitch) String() string {
+	var sb strings.Builder
+	sb.WriteString("(switch")
+	if op.opSubject != nil {
+		sb.WriteString(" ")
+		if op.key != "" {
+			sb.WriteString(op.key)
+			sb.WriteString(" = ")
+		}
+		sb.WriteString(op.opSubject.String())
+	}
+	for _, arm := range op.arms {
+		sb.WriteString(" ")
+		sb.WriteString(armTestString(arm))
+		sb.WriteString(" then ")
+		sb.WriteString(arm.Result.String())
+	}
+	if op.opDefault != nil {
+		sb.WriteString(" default ")
+		sb.WriteString(op.opDefault.String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// armTestString returns the string representation of the test of a switch arm (e.g. "case 1, 2" or "when x")
+func armTestString(arm SwitchArm) string {
+	switch arm.Type {
+	case ATEqual:
+		values := make([]string, 0, len(arm.Values))
+		for _, valueOp := range arm.Values {
+			values = append(values, valueOp.String())
+		}
+		return "case " + strings.Join(values, ", ")
+	case ATGuard:
+		return "when " + arm.Values[0].String()
+	case ATMatch:
+		return "case match " + arm.Values[0].String()
+	case ATRange:
+		var sb strings.Builder
+		sb.WriteString("case ")
+		if arm.Values[0] != nil {
+			sb.WriteString(arm.Values[0].String())
+		}
+		sb.WriteString("..")
+		if arm.Values[1] != nil {
+			sb.WriteString(arm.Values[1].String())
+		}
+		return sb.String()
+	default:
+		panic(fmt.Sprintf("unknown switch arm type %v", arm.Type))
+	}
+}
+
+func (op *exprSwitch) Children() []Expression {
+	var children []Expression
+	if op.opSubject != nil {
+		children = append(children, op.opSubject)
+	}
+	for _, arm := range op.arms {
+		for _, valueOp := range arm.Values {
+			if valueOp != nil {
+				children = append(children, valueOp)
+			}
+		}
+		children = append(children, arm.Result)
+	}
+	if op.opDefault != nil {
+		children = append(children, op.opDefault)
+	}
+	return children
+}
+
+func (op *exprSwitch) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, len(op.Children()))
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	next := func() Expression {
+		child := children[0]
+		children = children[1:]
+		return child
+	}
+	if op.opSubject != nil {
+		cp.opSubject = next()
+	}
+	cp.arms = make([]SwitchArm, 0, len(op.arms))
+	for _, arm := range op.arms {
+		values := make([]Expression, 0, len(arm.Values))
+		for _, valueOp := range arm.Values {
+			if valueOp != nil {
+				valueOp = next()
+			}
+			values = append(values, valueOp)
+		}
+		cp.arms = append(cp.arms, SwitchArm{Type: arm.Type, Values: values, Result: next()})
+	}
+	if op.opDefault != nil {
+		cp.opDefault = next()
+	}
+	return &cp, nil
+}
+
+// NewExprSwitch creates a switch Expression. The subject may be nil if only guard arms are used. The heap key
+// (where to store the subject) and the default Expression are optional (specify "" and nil respectively).
+// The result type of the switch Expression is the common result type of the arm results and the default Expression.
+// An error is returned if the result types differ or if an arm is invalid for the subject.
+func NewExprSwitch(opSubject Expression, arms []SwitchArm, opDefault Expression, key string,
+	line, col int) (Expression, error) {
+	if len(arms) == 0 {
+		return nil, fmt.Errorf("switch without arms")
+	}
+	if opSubject != nil {
+		adaptSwitchSubject(opSubject, arms)
+	}
+	checked := make([]SwitchArm, 0, len(arms))
+	results := make([]Expression, 0, len(arms)+1)
+	for i, arm := range arms {
+		arm, err := checkSwitchArm(arm, opSubject, line, col)
+		if err != nil {
+			return nil, fmt.Errorf("switch arm %d: %v", i+1, err)
+		}
+		checked = append(checked, arm)
+		results = append(results, arm.Result)
+	}
+	if opDefault != nil {
+		results = append(results, opDefault)
+	}
+	rt, err := unitType("switch", results)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" && opSubject == nil {
+		return nil, fmt.Errorf("switch key %s specified without a subject", key)
+	}
+	return &exprSwitch{
+		baseExpression: newBaseExpression(rt, line, col),
+		opSubject:      opSubject,
+		arms:           checked,
+		opDefault:      opDefault,
+		key:            key,
+	}, nil
+}
+
+func NewExprSwitchMust(opSubject Expression, arms []SwitchArm, opDefault Expression, key string,
+	line, col int) Expression {
+	expr, err := NewExprSwitch(opSubject, arms, opDefault, key, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating switch expression: %v", err))
+	}
+	return expr
+}
+
+// adaptSwitchSubject adapts a "dynamically typed" subject (e.g. reference) to the result type of the first equal or
+// range arm value not having the result type of the subject (in the same way as for compare). If the subject can't
+// be adapted it is left as is (and the arm is reported as invalid by checkSwitchArm()).
+func adaptSwitchSubject(opSubject Expression, arms []SwitchArm) {
+	st := opSubject.ResultType()
+	for _, arm := range arms {
+		if arm.Type != ATEqual && arm.Type != ATRange {
+			continue
+		}
+		for _, valueOp := range arm.Values {
+			if valueOp != nil && !acceptsResultType(valueOp, st) {
+				opSubject.ExpectedResultType(valueOp.ResultType())
+				return
+			}
+		}
+	}
+}
+
+// checkSwitchArm checks that an arm is valid for the subject. If the value of a match arm is a constant string it
+// is converted to a constant regexp.
+func checkSwitchArm(arm SwitchArm, opSubject Expression, line, col int) (SwitchArm, error) {
+	if arm.Result == nil {
+		return arm, fmt.Errorf("no result")
+	}
+	values := map[ArmType]int{ATEqual: -1, ATGuard: 1, ATMatch: 1, ATRange: 2}
+	count, ok := values[arm.Type]
+	if !ok {
+		return arm, fmt.Errorf("unknown arm type %v", arm.Type)
+	}
+	if (count < 0 && len(arm.Values) == 0) || (count > 0 && len(arm.Values) != count) {
+		return arm, fmt.Errorf("wrong number of values (%d) for %s arm", len(arm.Values), arm.Type)
+	}
+	if arm.Type == ATGuard {
+		if !arm.Values[0].ExpectedResultType(NewScalarTypeSignature(VTBoolean)) {
+			return arm, fmt.Errorf("guard must be a boolean (got %v)", arm.Values[0].ResultType())
+		}
+		return arm, nil
+	}
+
+	if opSubject == nil {
+		return arm, fmt.Errorf("%s arm without a subject", arm.Type)
+	}
+	st := opSubject.ResultType()
+	switch arm.Type {
+	case ATEqual:
+		for _, valueOp := range arm.Values {
+			if !valueOp.ExpectedResultType(st) {
+				return arm, fmt.Errorf("value %v must be of type %v (got %v)", valueOp, st, valueOp.ResultType())
+			}
+		}
+	case ATMatch:
+		if !st.IsValueType(VTString) {
+			return arm, fmt.Errorf("match requires a string subject (got %v)", st)
+		}
+		matcher := arm.Values[0]
+		constant, ok := matcher.(*exprConstant)
+		if ok && constant.ResultType().IsValueType(VTString) {
+			str := constant.c.Value.(string)
+			regexp, err := NewExprValueRegexp(str)
+			if err != nil {
+				return arm, fmt.Errorf("can't create regexp from %s: %v", str, err)
+			}
+			matcher = NewExprConstant(regexp, line, col)
+		}
+		if !matcher.ExpectedResultType(NewScalarTypeSignature(VTRegexp)) {
+			return arm, fmt.Errorf("match requires a regexp (got %v)", matcher.ResultType())
+		}
+		arm.Values = []Expression{matcher}
+	case ATRange:
+		if !VTMetadata.ComparableType(st) {
+			return arm, fmt.Errorf("range requires a comparable subject (got %v)", st)
+		}
+		if arm.Values[0] == nil && arm.Values[1] == nil {
+			return arm, fmt.Errorf("range without bounds")
+		}
+		for _, boundOp := range arm.Values {
+			if boundOp != nil && !boundOp.ExpectedResultType(st) {
+				return arm, fmt.Errorf("bound %v must be of type %v (got %v)", boundOp, st, boundOp.ResultType())
+			}
+		}
+	}
+	return arm, nil
+}
@@ -0,0 +1,181 @@
+package goexpr
+
+import (
+	"testing"
+)
+
+func TestExprSwitch_String(t *testing.T) {
+	l, c := 1, 2
+	str := func(s string) Expression {
+		return NewExprConstant(NewExprValueString(s), l, c)
+	}
+	integer := func(i int) Expression {
+		return NewExprConstant(NewExprValueInteger(i), l, c)
+	}
+	// The (untyped) subject reference is adapted to the type of the arm values
+	code := NewExprHeapReference("code", "code", l, c)
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"equal", NewExprSwitchMust(code, []SwitchArm{
+			{Type: ATEqual, Values: []Expression{integer(200), integer(204)}, Result: str("ok")},
+		}, str("other"), "", l, c),
+			`(switch code case 200, 204 then "ok" default "other")`},
+		{"range", NewExprSwitchMust(code, []SwitchArm{
+			{Type: ATRange, Values: []Expression{integer(400), integer(499)}, Result: str("client")},
+			{Type: ATRange, Values: []Expression{integer(500), nil}, Result: str("server")},
+		}, nil, "c", l, c),
+			`(switch c = code case 400..499 then "client" case 500.. then "server")`},
+		{"match", NewExprSwitchMust(NewExprHeapReference("name", "name", l, c), []SwitchArm{
+			{Type: ATMatch, Values: []Expression{str("^foo")}, Result: integer(1)},
+		}, nil, "", l, c),
+			`(switch name case match "^foo" then 1)`},
+		{"guard", NewExprSwitchMust(nil, []SwitchArm{
+			{Type: ATGuard, Values: []Expression{NewExprConstant(EvBooleanTrue, l, c)}, Result: integer(1)},
+		}, integer(2), "", l, c),
+			`(switch when true then 1 default 2)`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprSwitch_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	str := func(s string) Expression {
+		return NewExprConstant(NewExprValueString(s), l, c)
+	}
+	integer := func(i int) Expression {
+		return NewExprConstant(NewExprValueInteger(i), l, c)
+	}
+	intRef := func(key string) Expression {
+		ref := NewExprHeapReference(key, key, l, c)
+		ref.ExpectedResultType(NewScalarTypeSignature(VTInteger))
+		return ref
+	}
+	// Maps a status code to a category. The (untyped) subject reference is adapted to the type of the arm values.
+	category := func() Expression {
+		return NewExprSwitchMust(NewExprHeapReference("code", "code", l, c), []SwitchArm{
+			{Type: ATEqual, Values: []Expression{integer(200), integer(204)}, Result: str("ok")},
+			{Type: ATEqual, Values: []Expression{NewExprConstant(EvNilInteger, l, c)}, Result: str("missing")},
+			{Type: ATRange, Values: []Expression{integer(400), integer(499)}, Result: str("client")},
+			{Type: ATRange, Values: []Expression{integer(500), nil}, Result: str("server")},
+			{Type: ATGuard, Values: []Expression{NewExprCompareMust(CTLess, intRef("c"), integer(0), l, c)},
+				Result: str("invalid")},
+		}, str("other"), "c", l, c)
+	}
+	name := func() Expression {
+		return NewExprSwitchMust(NewExprHeapReference("name", "name", l, c), []SwitchArm{
+			{Type: ATMatch, Values: []Expression{str("^foo")}, Result: integer(1)},
+			{Type: ATMatch, Values: []Expression{str("bar$")}, Result: integer(2)},
+		}, nil, "", l, c)
+	}
+	tests := []struct {
+		name   string
+		op     Expression
+		values map[string]string
+		result Value
+	}{
+		{"equal", category(), map[string]string{"code": "204"}, NewExprValueString("ok")},
+		{"equalNil", category(), map[string]string{}, NewExprValueString("missing")},
+		{"rangeLow", category(), map[string]string{"code": "400"}, NewExprValueString("client")},
+		{"rangeHigh", category(), map[string]string{"code": "499"}, NewExprValueString("client")},
+		{"rangeOpen", category(), map[string]string{"code": "999"}, NewExprValueString("server")},
+		{"guard", category(), map[string]string{"code": "-1"}, NewExprValueString("invalid")},
+		{"default", category(), map[string]string{"code": "302"}, NewExprValueString("other")},
+		{"match", name(), map[string]string{"name": "foobar"}, NewExprValueInteger(1)},
+		{"matchSecond", name(), map[string]string{"name": "a bar"}, NewExprValueInteger(2)},
+		{"noMatch", name(), map[string]string{"name": "baz"}, EvNilInteger},
+		{"matchNil", name(), map[string]string{}, EvNilInteger},
+		{"guardNil", NewExprSwitchMust(nil, []SwitchArm{
+			{Type: ATGuard, Values: []Expression{NewExprConstant(EvNilBoolean, l, c)}, Result: integer(1)},
+		}, integer(2), "", l, c), map[string]string{}, NewExprValueInteger(2)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !test.op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", test.op.ResultType(), test.result.Type)
+			}
+			res, err := test.op.Evaluate(newTestRequestContext(test.values))
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestNewExprSwitch_Error(t *testing.T) {
+	l, c := 1, 2
+	str := NewExprConstant(NewExprValueString("foo"), l, c)
+	integer := NewExprConstant(NewExprValueInteger(1), l, c)
+	regexp := NewExprConstant(NewExprValueRegexpMust("foo"), l, c)
+	tests := []struct {
+		name    string
+		subject Expression
+		arms    []SwitchArm
+		def     Expression
+		key     string
+	}{
+		{"noArms", str, []SwitchArm{}, nil, ""},
+		{"unknownArm", str, []SwitchArm{{Type: ArmType("unknown"), Values: []Expression{str}, Result: str}}, nil, ""},
+		{"noResult", str, []SwitchArm{{Type: ATEqual, Values: []Expression{str}}}, nil, ""},
+		{"noValues", str, []SwitchArm{{Type: ATEqual, Values: []Expression{}, Result: str}}, nil, ""},
+		{"equalType", str, []SwitchArm{{Type: ATEqual, Values: []Expression{integer}, Result: str}}, nil, ""},
+		{"resultType", str, []SwitchArm{{Type: ATEqual, Values: []Expression{str}, Result: str}}, integer, ""},
+		{"matchSubject", integer, []SwitchArm{{Type: ATMatch, Values: []Expression{str}, Result: str}}, nil, ""},
+		{"matchRegexp", str, []SwitchArm{{Type: ATMatch, Values: []Expression{
+			NewExprConstant(NewExprValueString("("), l, c)}, Result: str}}, nil, ""},
+		{"rangeNotComparable", regexp, []SwitchArm{{Type: ATRange, Values: []Expression{regexp, regexp},
+			Result: str}}, nil, ""},
+		{"rangeNoBounds", integer, []SwitchArm{{Type: ATRange, Values: []Expression{nil, nil}, Result: str}},
+			nil, ""},
+		{"guardType", nil, []SwitchArm{{Type: ATGuard, Values: []Expression{str}, Result: str}}, nil, ""},
+		{"noSubject", nil, []SwitchArm{{Type: ATEqual, Values: []Expression{str}, Result: str}}, nil, ""},
+		{"keyNoSubject", nil, []SwitchArm{{Type: ATGuard, Values: []Expression{
+			NewExprConstant(EvBooleanTrue, l, c)}, Result: str}}, nil, "key"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprSwitch(test.subject, test.arms, test.def, test.key, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestExprSwitch_WithChildren(t *testing.T) {
+	l, c := 1, 2
+	integer := func(i int) Expression {
+		return NewExprConstant(NewExprValueInteger(i), l, c)
+	}
+	op := NewExprSwitchMust(integer(5), []SwitchArm{
+		{Type: ATRange, Values: []Expression{nil, integer(10)}, Result: integer(1)},
+	}, integer(2), "", l, c)
+	rewritten, err := Rewrite(op, func(e Expression) (Expression, error) {
+		constant, ok := e.(*exprConstant)
+		if ok && constant.c.Value.(int) == 10 {
+			return integer(3), nil
+		}
+		return e, nil
+	})
+	if err != nil {
+		t.Errorf("unexpected rewrite error: %v", err)
+		return
+	}
+	expected := `(switch 5 case ..3 then 1 default 2)`
+	if rewritten.String() != expected {
+		t.Errorf("wrong rewrite result.\nactual:   %v\nexpected: %v", rewritten, expected)
+	}
+}
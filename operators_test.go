@@ -1,6 +1,7 @@
 package goexpr
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -76,11 +77,28 @@ func TestOperator_String(t *testing.T) {
 		{"OpLogicalNotTrue", NewExprLogicalUnary(LTNot, NewExprConstant(NewExprValueBoolean(true), l, c),
 			l, c),
 			`(not true)`},
+		{"OpLogicalAndThreeValued", NewExprLogicalMode(LTAnd, LMThreeValued,
+			NewExprConstant(NewExprValueBoolean(true), l, c), NewExprConstant(NewExprValueBoolean(true), l, c), l, c),
+			`(true and3 true)`},
+		{"OpLogicalOrTwoValued", NewExprLogicalMode(LTOr, LMTwoValued,
+			NewExprConstant(NewExprValueBoolean(true), l, c), NewExprConstant(NewExprValueBoolean(true), l, c), l, c),
+			`(true or2 true)`},
+		{"OpLogicalNotTwoValued", NewExprLogicalMode(LTNot, LMTwoValued,
+			NewExprConstant(NewExprValueBoolean(true), l, c), nil, l, c),
+			`(not2 true)`},
 		// reference ---------------------------------------
 		{"OpHeapRef string", NewExprHeapReference("ref", "my/ref", l, c),
 			`my/ref`},
 		{"OpHeapRef path lookup", NewExprHeapReference("state", compilePathMust("state"), l, c),
 			`state`},
+		{"OpValueRef index", NewExprValueReference("first", -1, NewExprHeapReference("items", "items", l, c), l, c),
+			`items[-1]`},
+		{"OpValueRef slice", NewExprValueReference("prefix", Slice{Start: 0, End: 3},
+			NewExprHeapReference("name", "name", l, c), l, c),
+			`name[0:3]`},
+		{"OpValueRef slice open end", NewExprValueReference("suffix", Slice{Start: 2, OpenEnd: true},
+			NewExprHeapReference("name", "name", l, c), l, c),
+			`name[2:]`},
 		// search ---------------------------------------
 		{"OpSearchExistListFound", NewExprSearch(
 			NewExprConstant(NewExprValueString("bar"), l, c),
@@ -199,6 +217,16 @@ func TestOperator_Evaluate_Ok(t *testing.T) {
 		{"OpCompareGreaterEqualFalse", NewExprCompareMust(CTGreaterEqual, NewExprConstant(NewExprValueInteger(1), l, c),
 			NewExprConstant(NewExprValueInteger(2), l, c), l, c), NewExprValueBoolean(false)},
 
+		{"OpCompareLessString", NewExprCompareMust(CTLess, NewExprConstant(NewExprValueString("bar"), l, c),
+			NewExprConstant(NewExprValueString("m"), l, c), l, c), NewExprValueBoolean(true)},
+		{"OpCompareGreaterBoolean", NewExprCompareMust(CTGreater, NewExprConstant(EvBooleanTrue, l, c),
+			NewExprConstant(EvBooleanFalse, l, c), l, c), NewExprValueBoolean(true)},
+		{"OpCompareLessList", NewExprCompareMust(CTLess,
+			NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+				NewExprValueString("a"), NewExprValueString("b")}), l, c),
+			NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+				NewExprValueString("a"), NewExprValueString("c")}), l, c), l, c), NewExprValueBoolean(true)},
+
 		{"OpCompareMatchTrue", NewExprCompareMust(CTMatch, NewExprConstant(NewExprValueString("123"), l, c),
 			NewExprConstant(NewExprValueRegexpMust("[0-9]{3}"), l, c), l, c), NewExprValueBoolean(true)},
 		{"OpCompareMatchFalse", NewExprCompareMust(CTMatch, NewExprConstant(NewExprValueString("no match"), l, c),
@@ -247,6 +275,26 @@ func TestOperator_Evaluate_Ok(t *testing.T) {
 			nil,
 			"k1", l, c),
 			EvNilString},
+		{"OpForMapKeyOrder", NewExprFor(
+			NewExprConstant(NewExprValueMap(NewScalarTypeSignature(VTString), map[string]Value{
+				"b": NewExprValueString("second"),
+				"c": NewExprValueString("third"),
+				"a": NewExprValueString("first"),
+			}), l, c),
+			NewExprHeapReference("loop", "k1", l, c),
+			NewExprConstant(NewExprValueString("second"), l, c),
+			"k1", l, c),
+			NewExprValueString("second")},
+		{"OpForMapLast", NewExprFor(
+			NewExprConstant(NewExprValueMap(NewScalarTypeSignature(VTString), map[string]Value{
+				"b": NewExprValueString("second"),
+				"c": NewExprValueString("third"),
+				"a": NewExprValueString("first"),
+			}), l, c),
+			NewExprHeapReference("loop", "k1", l, c),
+			nil,
+			"k1", l, c),
+			NewExprValueString("third")},
 		// if ---------------------------------------
 		{"OpIfThen", NewExprIf(NewExprConstant(NewExprValueBoolean(true), l, c),
 			NewExprConstant(NewExprValueString("then"), l, c), nil, l, c),
@@ -684,3 +732,130 @@ func TestEvaluate_OpReference_Heap(t *testing.T) {
 		t.Errorf("wrong request context reference heap (key %s).\nactual:   %v\nexprected: %v", key, res, valueI)
 	}
 }
+
+func TestEvaluate_OpReference_ValueIndex(t *testing.T) {
+	l, c := 1, 2
+	reqCtx := newEmptyTestRequestContext()
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	items := NewExprConstant(NewExprValueList(tsInteger, []Value{
+		NewExprValueInteger(1),
+		NewExprValueInteger(2),
+		NewExprValueInteger(3),
+	}), l, c)
+	name := NewExprConstant(NewExprValueString("foobar"), l, c)
+	tests := []struct {
+		name   string
+		op     Expression
+		result Value
+	}{
+		{"listIndex", NewExprValueReference("first", 0, items, l, c), NewExprValueInteger(1)},
+		{"listIndexLast", NewExprValueReference("last", -1, items, l, c), NewExprValueInteger(3)},
+		{"listIndexOutOfRange", NewExprValueReference("missing", 5, items, l, c), EvNilInteger},
+		{"listSlice", NewExprValueReference("tail", Slice{Start: 1, OpenEnd: true}, items, l, c),
+			NewExprValueList(tsInteger, []Value{NewExprValueInteger(2), NewExprValueInteger(3)})},
+		{"stringIndex", NewExprValueReference("initial", 0, name, l, c), NewExprValueString("f")},
+		{"stringSlice", NewExprValueReference("prefix", Slice{Start: 0, End: 3}, name, l, c),
+			NewExprValueString("foo")},
+		{"stringSliceOutOfRange", NewExprValueReference("prefix", Slice{Start: 3, End: 30}, name, l, c),
+			NewExprValueString("bar")},
+		{"nilSource", NewExprValueReference("first", 0,
+			NewExprConstant(NewNilExprValue(NewCompositeTypeSignature(VTList, tsInteger)), l, c), l, c),
+			EvNilInteger},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !test.op.ResultType().Equal(test.result.Type) {
+				t.Errorf("wrong result type.\nactual:   %v\nexpected: %v", test.op.ResultType(), test.result.Type)
+			}
+			res, err := test.op.Evaluate(reqCtx)
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestEvaluate_OpLogicalMode(t *testing.T) {
+	l, c := 1, 2
+	reqCtx := newEmptyTestRequestContext()
+	operand := map[string]Value{"T": EvBooleanTrue, "F": EvBooleanFalse, "N": EvNilBoolean}
+	// Expected results for the operands (left right) in each logic mode
+	tests := []struct {
+		lt         LogicalType
+		operands   string
+		propagate  Value
+		threeValue Value
+		twoValue   Value
+	}{
+		{LTAnd, "TT", EvBooleanTrue, EvBooleanTrue, EvBooleanTrue},
+		{LTAnd, "TF", EvBooleanFalse, EvBooleanFalse, EvBooleanFalse},
+		{LTAnd, "TN", EvNilBoolean, EvNilBoolean, EvBooleanFalse},
+		{LTAnd, "FN", EvBooleanFalse, EvBooleanFalse, EvBooleanFalse},
+		{LTAnd, "NF", EvNilBoolean, EvBooleanFalse, EvBooleanFalse},
+		{LTAnd, "NT", EvNilBoolean, EvNilBoolean, EvBooleanFalse},
+		{LTAnd, "NN", EvNilBoolean, EvNilBoolean, EvBooleanFalse},
+		{LTOr, "FF", EvBooleanFalse, EvBooleanFalse, EvBooleanFalse},
+		{LTOr, "FT", EvBooleanTrue, EvBooleanTrue, EvBooleanTrue},
+		{LTOr, "FN", EvNilBoolean, EvNilBoolean, EvBooleanFalse},
+		{LTOr, "TN", EvBooleanTrue, EvBooleanTrue, EvBooleanTrue},
+		{LTOr, "NT", EvNilBoolean, EvBooleanTrue, EvBooleanTrue},
+		{LTOr, "NF", EvNilBoolean, EvNilBoolean, EvBooleanFalse},
+		{LTOr, "NN", EvNilBoolean, EvNilBoolean, EvBooleanFalse},
+		{LTNot, "T", EvBooleanFalse, EvBooleanFalse, EvBooleanFalse},
+		{LTNot, "N", EvNilBoolean, EvNilBoolean, EvBooleanTrue},
+	}
+	for _, test := range tests {
+		for _, mode := range []LogicMode{LMPropagate, LMThreeValued, LMTwoValued} {
+			expected := map[LogicMode]Value{
+				LMPropagate:   test.propagate,
+				LMThreeValued: test.threeValue,
+				LMTwoValued:   test.twoValue,
+			}[mode]
+			t.Run(fmt.Sprintf("%s %s %s", test.lt, test.operands, mode), func(t *testing.T) {
+				leftOp := NewExprConstant(operand[test.operands[:1]], l, c)
+				var rightOp Expression
+				if len(test.operands) > 1 {
+					rightOp = NewExprConstant(operand[test.operands[1:]], l, c)
+				}
+				op := NewExprLogicalMode(test.lt, mode, leftOp, rightOp, l, c)
+				res, err := op.Evaluate(reqCtx)
+				if err != nil {
+					t.Errorf("unexprected evaluation error: %v", err)
+					return
+				}
+				if !res.Equal(expected) {
+					t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, expected)
+				}
+			})
+		}
+	}
+}
+
+func TestNewExprCompare_Error(t *testing.T) {
+	l, c := 1, 2
+	str := NewExprConstant(NewExprValueString("foo"), l, c)
+	integer := NewExprConstant(NewExprValueInteger(1), l, c)
+	regexp := NewExprConstant(NewExprValueRegexpMust("foo"), l, c)
+	tests := []struct {
+		name  string
+		ct    CompareType
+		left  Expression
+		right Expression
+	}{
+		{"differentTypes", CTLess, str, integer},
+		{"notComparable", CTGreater, regexp, regexp},
+		{"invalidRegexp", CTMatch, str, NewExprConstant(NewExprValueString("("), l, c)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprCompare(test.ct, test.left, test.right, l, c)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
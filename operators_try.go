@@ -0,0 +1,140 @@
+package goexpr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// exprTry evaluates an Expression and, if the evaluation returns an error, evaluates a fallback (catch) Expression
+// instead. The result of the Expression is the result of the evaluated Expression.
+// If error kinds are specified only errors of these kinds (see ErrorKindOf()) are caught. Other errors are
+// returned as is.
+// If an error name is specified the error message (a string) is bound to the name when evaluating the catch
+// Expression (in the same way as for a let Expression).
+// Note that assignments done by the try Expression before the error occurred are not undone.
+type exprTry struct {
+	baseExpression
+	opTry   Expression
+	opCatch Expression
+	// The error kinds caught (all errors are caught if empty)
+	kinds []ErrorKind
+	// The name the error message is bound to (may be empty)
+	errName string
+}
+
+func (op *exprTry) Evaluate(recCtx RequestContext) (Value, error) {
+	value, err := op.opTry.Evaluate(recCtx)
+	if err == nil {
+		return value, nil
+	}
+	if !op.catches(err) {
+		return op.nilResult(), err
+	}
+	if op.errName == "" {
+		return op.opCatch.Evaluate(recCtx)
+	}
+	letCtx := &letRequestContext{
+		parent: recCtx,
+		names:  []string{op.errName},
+		values: []Value{NewExprValueString(err.Error())},
+	}
+	return op.opCatch.Evaluate(letCtx)
+}
+
+// catches returns true if the error is caught by the try Expression
+func (op *exprTry) catches(err error) bool {
+	if len(op.kinds) == 0 {
+		return true
+	}
+	kind := ErrorKindOf(err)
+	for _, k := range op.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// catchClause returns the string representation of the error kinds and the error name (e.g. "[reference] as err ")
+func (op *exprTry) catchClause() string {
+	var sb strings.Builder
+	if len(op.kinds) > 0 {
+		kinds := make([]string, 0, len(op.kinds))
+		for _, kind := range op.kinds {
+			kinds = append(kinds, string(kind))
+		}
+		sb.WriteString("[")
+		sb.WriteString(strings.Join(kinds, " "))
+		sb.WriteString("] ")
+	}
+	if op.errName != "" {
+		sb.WriteString("as ")
+		sb.WriteString(op.errName)
+		sb.WriteString(" ")
+	}
+	return sb.String()
+}
+
+func (op *exprTry) String() string {
+	var sb strings.Builder
+	sb.WriteString("(try ")
+	sb.WriteString(op.opTry.String())
+	sb.WriteString(" catch ")
+	sb.WriteString(op.catchClause())
+	sb.WriteString(op.opCatch.String())
+	sb.WriteString(")")
+	return sb.String()
+}
+
+func (op *exprTry) Children() []Expression {
+	return []Expression{op.opTry, op.opCatch}
+}
+
+func (op *exprTry) WithChildren(children []Expression) (Expression, error) {
+	err := checkChildren(children, 2)
+	if err != nil {
+		return nil, err
+	}
+	cp := *op
+	cp.opTry, cp.opCatch = children[0], children[1]
+	return &cp, nil
+}
+
+// NewExprTry creates a try Expression. The error kinds caught and the error name are optional (specify nil and ""
+// respectively). The heap references to the error name in the catch Expression get the result type string.
+// An error is returned if the Expressions have different result types or if an error kind is unknown.
+func NewExprTry(opTry, opCatch Expression, kinds []ErrorKind, errName string, line, col int) (Expression, error) {
+	for _, kind := range kinds {
+		switch kind {
+		case EKBudget, EKConversion, EKOther, EKReference:
+		default:
+			return nil, fmt.Errorf("unknown error kind %v", kind)
+		}
+	}
+	if errName != "" {
+		var err error
+		opCatch, err = bindReferences(opCatch, map[string]TypeSignature{errName: NewScalarTypeSignature(VTString)})
+		if err != nil {
+			return nil, err
+		}
+	}
+	rt, err := unitType("try", []Expression{opTry, opCatch})
+	if err != nil {
+		return nil, err
+	}
+	return &exprTry{
+		baseExpression: newBaseExpression(rt, line, col),
+		opTry:          opTry,
+		opCatch:        opCatch,
+		kinds:          append([]ErrorKind(nil), kinds...),
+		errName:        errName,
+	}, nil
+}
+
+func NewExprTryMust(opTry, opCatch Expression, kinds []ErrorKind, errName string, line, col int) Expression {
+	expr, err := NewExprTry(opTry, opCatch, kinds, errName, line, col)
+	if err != nil {
+		panic(fmt.Sprintf("error creating try expression: %v", err))
+	}
+	return expr
+}
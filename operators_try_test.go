@@ -0,0 +1,119 @@
+package goexpr
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExprTry_String(t *testing.T) {
+	l, c := 1, 2
+	ref := NewExprHeapReference("count", "count", l, c)
+	zero := NewExprConstant(NewExprValueString("0"), l, c)
+	tests := []struct {
+		name string
+		op   Expression
+		str  string
+	}{
+		{"catchAll", NewExprTryMust(ref, zero, nil, "", l, c), `(try count catch "0")`},
+		{"kinds", NewExprTryMust(ref, zero, []ErrorKind{EKConversion, EKReference}, "", l, c),
+			`(try count catch [conversion reference] "0")`},
+		{"errName", NewExprTryMust(ref, NewExprHeapReference("err", "err", l, c), nil, "err", l, c),
+			`(try count catch as err err)`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			str := test.op.String()
+			if str != test.str {
+				t.Errorf("wrong string result.\nactual:   %v\nexpected: %v", str, test.str)
+			}
+		})
+	}
+}
+
+func TestExprTry_Evaluate(t *testing.T) {
+	l, c := 1, 2
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	registry := NewFunctionRegistry()
+	fail := func(name string, err error) {
+		registry.Register(Function{
+			Name:   name,
+			Result: tsInteger,
+			Impl: func(args []Value) (Value, error) {
+				return EvNilInteger, err
+			},
+		})
+	}
+	fail("budget", fmt.Errorf("too expensive: %w", ErrBudgetExceeded))
+	fail("other", fmt.Errorf("failed"))
+	intRef := func(key interface{}) Expression {
+		ref := NewExprHeapReference("ref", key, l, c)
+		ref.ExpectedResultType(tsInteger)
+		return ref
+	}
+	zero := NewExprConstant(NewExprValueInteger(0), l, c)
+	tests := []struct {
+		name   string
+		op     Expression
+		result Value
+		err    bool
+	}{
+		{"noError", NewExprTryMust(intRef("count"), zero, nil, "", l, c), NewExprValueInteger(5), false},
+		{"conversion", NewExprTryMust(intRef("name"), zero, nil, "", l, c), NewExprValueInteger(0), false},
+		{"conversionCaught", NewExprTryMust(intRef("name"), zero, []ErrorKind{EKConversion}, "", l, c),
+			NewExprValueInteger(0), false},
+		{"conversionNotCaught", NewExprTryMust(intRef("name"), zero, []ErrorKind{EKReference}, "", l, c),
+			EvNilInteger, true},
+		{"referenceCaught", NewExprTryMust(intRef(1), zero, []ErrorKind{EKReference}, "", l, c),
+			NewExprValueInteger(0), false},
+		{"budgetCaught", NewExprTryMust(NewExprCallMust(registry, "budget", nil, l, c), zero,
+			[]ErrorKind{EKBudget}, "", l, c),
+			NewExprValueInteger(0), false},
+		{"otherNotCaught", NewExprTryMust(NewExprCallMust(registry, "other", nil, l, c), zero,
+			[]ErrorKind{EKBudget, EKConversion, EKReference}, "", l, c),
+			EvNilInteger, true},
+		{"otherCaught", NewExprTryMust(NewExprCallMust(registry, "other", nil, l, c), zero,
+			[]ErrorKind{EKOther}, "", l, c),
+			NewExprValueInteger(0), false},
+		{"errName", NewExprTryMust(NewExprCallMust(registry, "other", nil, l, c),
+			NewExprStringMust(SFLength, []Expression{NewExprHeapReference("err", "err", l, c)}, l, c),
+			nil, "err", l, c),
+			NewExprValueInteger(len("error calling function other (1:2): failed")), false},
+		{"nested", NewExprTryMust(
+			NewExprTryMust(intRef("name"), intRef(1), []ErrorKind{EKConversion}, "", l, c),
+			zero, []ErrorKind{EKReference}, "", l, c),
+			NewExprValueInteger(0), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reqCtx := newTestRequestContext(map[string]string{"count": "5", "name": "foo"})
+			res, err := test.op.Evaluate(reqCtx)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected evaluation error")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexprected evaluation error: %v", err)
+				return
+			}
+			if !res.Equal(test.result) {
+				t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, test.result)
+			}
+		})
+	}
+}
+
+func TestNewExprTry_Error(t *testing.T) {
+	l, c := 1, 2
+	str := NewExprConstant(NewExprValueString("foo"), l, c)
+	integer := NewExprConstant(NewExprValueInteger(1), l, c)
+	_, err := NewExprTry(str, integer, nil, "", l, c)
+	if err == nil {
+		t.Errorf("expected error for different result types")
+	}
+	_, err = NewExprTry(str, str, []ErrorKind{ErrorKind("unknown")}, "", l, c)
+	if err == nil {
+		t.Errorf("expected error for unknown error kind")
+	}
+}
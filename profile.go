@@ -0,0 +1,294 @@
+package goexpr
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProfileEntry holds the profile data for a single expression.
+type ProfileEntry struct {
+	// The source position of the expression
+	Line int
+	Col  int
+	// Short description of the expression (e.g. "if" or "reference my/ref")
+	Description string
+	// Number of times the expression was evaluated
+	Calls int
+	// Total time spent evaluating the expression including its sub-expressions
+	Cum time.Duration
+	// Total time spent evaluating the expression excluding its sub-expressions
+	Self time.Duration
+}
+
+// Profiler records call counts and evaluation times for each expression in an expression tree.
+// An expression tree is profiled by evaluating the instrumented expression returned by Instrument().
+// Note that a profiler must not be used by concurrent evaluations.
+type Profiler struct {
+	start time.Time
+	// Function returning the current time. Replaceable in tests.
+	now func() time.Time
+	// Profiled expressions in the order they were instrumented
+	exprs   []Expression
+	ids     map[Expression]int
+	entries map[Expression]*ProfileEntry
+	// The stack of expressions currently being evaluated
+	stack []profileFrame
+	// Samples keyed by the stack of expression ids (see profileSample)
+	samples map[string]*profileSample
+}
+
+type profileFrame struct {
+	expr  Expression
+	start time.Time
+	// Time spent evaluating sub-expressions
+	children time.Duration
+}
+
+// profileSample holds the number of calls and the self time for a specific stack of expressions
+type profileSample struct {
+	// Expression ids with the leaf expression first
+	stack []int
+	calls int
+	self  time.Duration
+}
+
+// NewProfiler creates a new profiler.
+func NewProfiler() *Profiler {
+	return newProfiler(time.Now)
+}
+
+func newProfiler(now func() time.Time) *Profiler {
+	return &Profiler{
+		start:   now(),
+		now:     now,
+		ids:     make(map[Expression]int),
+		entries: make(map[Expression]*ProfileEntry),
+		samples: make(map[string]*profileSample),
+	}
+}
+
+// Instrument returns an instrumented copy of the expression tree. Each time the instrumented expression is
+// evaluated the calls and evaluation times for each evaluated expression are recorded by the profiler. The profile
+// data is recorded for the expressions in the original expression tree.
+func (p *Profiler) Instrument(expr Expression) (Expression, error) {
+	Inspect(expr, func(e Expression) bool {
+		if e == nil {
+			return true
+		}
+		if _, ok := p.ids[e]; !ok {
+			p.ids[e] = len(p.exprs)
+			p.exprs = append(p.exprs, e)
+			p.entries[e] = &ProfileEntry{Line: e.Line(), Col: e.Col(), Description: exprDescription(e)}
+		}
+		return true
+	})
+	return instrument(expr, p.hook)
+}
+
+func (p *Profiler) hook(orig Expression, eval func() (Value, error)) (Value, error) {
+	p.stack = append(p.stack, profileFrame{expr: orig, start: p.now()})
+	value, err := eval()
+	frame := p.stack[len(p.stack)-1]
+	duration := p.now().Sub(frame.start)
+	self := duration - frame.children
+
+	// Record the expression entry
+	entry := p.entries[orig]
+	entry.Calls++
+	entry.Cum += duration
+	entry.Self += self
+
+	// Record the sample for the current stack
+	ids := make([]int, 0, len(p.stack))
+	keys := make([]string, 0, len(p.stack))
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		id := p.ids[p.stack[i].expr]
+		ids = append(ids, id)
+		keys = append(keys, strconv.Itoa(id))
+	}
+	key := strings.Join(keys, ",")
+	sample, ok := p.samples[key]
+	if !ok {
+		sample = &profileSample{stack: ids}
+		p.samples[key] = sample
+	}
+	sample.calls++
+	sample.self += self
+
+	p.stack = p.stack[:len(p.stack)-1]
+	if len(p.stack) > 0 {
+		p.stack[len(p.stack)-1].children += duration
+	}
+	return value, err
+}
+
+// Entries returns the profile data for all profiled expressions sorted by source position.
+func (p *Profiler) Entries() []ProfileEntry {
+	entries := make([]ProfileEntry, 0, len(p.exprs))
+	for _, expr := range p.exprs {
+		entries = append(entries, *p.entries[expr])
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Line != entries[j].Line {
+			return entries[i].Line < entries[j].Line
+		}
+		return entries[i].Col < entries[j].Col
+	})
+	return entries
+}
+
+// WriteProfile writes the recorded profile data as a gzipped pprof profile (see
+// https://github.com/google/pprof/blob/master/proto/profile.proto). The profile may be analyzed using
+// "go tool pprof". Each expression is represented as a function named by the expression description and source
+// position. The source name is used as the file name of the functions.
+func (p *Profiler) WriteProfile(w io.Writer, sourceName string) error {
+	var strs profileStrings
+	strs.index("")
+
+	var pb protoBuffer
+	// Sample types (calls and self time)
+	for _, st := range [][2]string{{"calls", "count"}, {"time", "nanoseconds"}} {
+		var vt protoBuffer
+		vt.int64(1, int64(strs.index(st[0])))
+		vt.int64(2, int64(strs.index(st[1])))
+		pb.message(1, vt)
+	}
+	// Samples in a stable order
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sample := p.samples[key]
+		locations := make([]uint64, 0, len(sample.stack))
+		for _, id := range sample.stack {
+			locations = append(locations, uint64(id+1))
+		}
+		var sb protoBuffer
+		sb.packedUint64(1, locations)
+		sb.packedInt64(2, []int64{int64(sample.calls), sample.self.Nanoseconds()})
+		pb.message(2, sb)
+	}
+	// One location and function for each expression
+	for id, expr := range p.exprs {
+		var line protoBuffer
+		line.uint64(1, uint64(id+1))
+		line.int64(2, int64(expr.Line()))
+		var loc protoBuffer
+		loc.uint64(1, uint64(id+1))
+		loc.message(4, line)
+		pb.message(4, loc)
+	}
+	for id, expr := range p.exprs {
+		name := fmt.Sprintf("%s (%d:%d)", exprDescription(expr), expr.Line(), expr.Col())
+		var fn protoBuffer
+		fn.uint64(1, uint64(id+1))
+		fn.int64(2, int64(strs.index(name)))
+		fn.int64(3, int64(strs.index(name)))
+		fn.int64(4, int64(strs.index(sourceName)))
+		fn.int64(5, int64(expr.Line()))
+		pb.message(5, fn)
+	}
+	for _, s := range strs.strings {
+		pb.string(6, s)
+	}
+	pb.int64(9, p.start.UnixNano())
+	pb.int64(10, p.now().Sub(p.start).Nanoseconds())
+	// Period type is the time sample type
+	var period protoBuffer
+	period.int64(1, int64(strs.index("time")))
+	period.int64(2, int64(strs.index("nanoseconds")))
+	pb.message(11, period)
+	pb.int64(12, 1)
+
+	gz := gzip.NewWriter(w)
+	_, err := gz.Write(pb.buf)
+	if err != nil {
+		return fmt.Errorf("error writing profile: %v", err)
+	}
+	return gz.Close()
+}
+
+// profileStrings is the string table of a pprof profile
+type profileStrings struct {
+	strings []string
+	indexes map[string]int
+}
+
+func (ps *profileStrings) index(s string) int {
+	if ps.indexes == nil {
+		ps.indexes = make(map[string]int)
+	}
+	i, ok := ps.indexes[s]
+	if !ok {
+		i = len(ps.strings)
+		ps.indexes[s] = i
+		ps.strings = append(ps.strings, s)
+	}
+	return i
+}
+
+// protoBuffer is a minimal protocol buffer encoder supporting the wire types needed for pprof profiles
+type protoBuffer struct {
+	buf []byte
+}
+
+func (pb *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		pb.buf = append(pb.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	pb.buf = append(pb.buf, byte(x))
+}
+
+func (pb *protoBuffer) tag(field int, wireType int) {
+	pb.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (pb *protoBuffer) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	pb.tag(field, 0)
+	pb.varint(x)
+}
+
+func (pb *protoBuffer) int64(field int, x int64) {
+	pb.uint64(field, uint64(x))
+}
+
+func (pb *protoBuffer) bytes(field int, b []byte) {
+	pb.tag(field, 2)
+	pb.varint(uint64(len(b)))
+	pb.buf = append(pb.buf, b...)
+}
+
+func (pb *protoBuffer) string(field int, s string) {
+	pb.bytes(field, []byte(s))
+}
+
+func (pb *protoBuffer) message(field int, msg protoBuffer) {
+	pb.bytes(field, msg.buf)
+}
+
+func (pb *protoBuffer) packedUint64(field int, xs []uint64) {
+	var packed protoBuffer
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	pb.bytes(field, packed.buf)
+}
+
+func (pb *protoBuffer) packedInt64(field int, xs []int64) {
+	var packed protoBuffer
+	for _, x := range xs {
+		packed.varint(uint64(x))
+	}
+	pb.bytes(field, packed.buf)
+}
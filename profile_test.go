@@ -0,0 +1,76 @@
+package goexpr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// testClock returns a clock function that advances one millisecond each time it is called
+func testClock() func() time.Time {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		t := now
+		now = now.Add(time.Millisecond)
+		return t
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	op := NewExprIf(NewExprConstant(EvBooleanTrue, 1, 4), NewExprConstant(NewExprValueString("then"), 2, 1),
+		nil, 1, 1)
+	p := newProfiler(testClock())
+	inst, err := p.Instrument(op)
+	if err != nil {
+		t.Errorf("unexpected instrument error: %v", err)
+		return
+	}
+	res, err := inst.Evaluate(newEmptyTestRequestContext())
+	if err != nil {
+		t.Errorf("unexpected evaluation error: %v", err)
+		return
+	}
+	if !res.Equal(NewExprValueString("then")) {
+		t.Errorf("wrong evaluation result: %v", res)
+	}
+
+	expected := []ProfileEntry{
+		{1, 1, "if", 1, 5 * time.Millisecond, 3 * time.Millisecond},
+		{1, 4, "constant true", 1, time.Millisecond, time.Millisecond},
+		{2, 1, `constant "then"`, 1, time.Millisecond, time.Millisecond},
+	}
+	entries := p.Entries()
+	if len(entries) != len(expected) {
+		t.Errorf("wrong number of entries (%d != %d)", len(entries), len(expected))
+		return
+	}
+	for i, entry := range entries {
+		if entry != expected[i] {
+			t.Errorf("wrong entry %d.\nactual:   %+v\nexpected: %+v", i, entry, expected[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	err = p.WriteProfile(&buf, "rule.txt")
+	if err != nil {
+		t.Errorf("unexpected error writing profile: %v", err)
+		return
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Errorf("profile is not gzipped: %v", err)
+		return
+	}
+	data, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Errorf("error reading profile: %v", err)
+		return
+	}
+	for _, s := range []string{"calls", "nanoseconds", "if (1:1)", `constant "then" (2:1)`, "rule.txt"} {
+		if !bytes.Contains(data, []byte(s)) {
+			t.Errorf("profile doesn't contain string %q", s)
+		}
+	}
+}
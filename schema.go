@@ -0,0 +1,256 @@
+package goexpr
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaVersion is the JSON Schema version of the schemas generated by JSONSchema()
+const JSONSchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// decimalPattern is the JSON Schema pattern for a decimal encoded as a string (see ParseDecimal())
+const decimalPattern = `^[+-]?[0-9]+(\.[0-9]+)?$`
+
+// JSONSchema returns a JSON Schema describing the JSON documents matching a type signature. The schema is returned
+// as a go value that may be marshalled using encoding/json.
+// Lists are described as arrays and maps as objects with additional properties (of the unit type). A regexp is
+// described as a string with format "regex" and a decimal as a string matching decimalPattern (decimals are
+// encoded as strings to avoid the loss of precision of JSON numbers). An any value is described by an empty schema
+// (matching any JSON value).
+// As any value may be nil (see Value.Nil()) null is allowed for all types.
+func JSONSchema(ts TypeSignature) map[string]interface{} {
+	schema := jsonSchema(ts)
+	schema["$schema"] = JSONSchemaVersion
+	return schema
+}
+
+func jsonSchema(ts TypeSignature) map[string]interface{} {
+	schema := make(map[string]interface{})
+	var jsonType string
+	switch ts.BaseType {
+	case VTAny:
+		return schema
+	case VTBoolean:
+		jsonType = "boolean"
+	case VTDecimal:
+		jsonType = "string"
+		schema["pattern"] = decimalPattern
+	case VTInteger:
+		jsonType = "integer"
+	case VTList:
+		jsonType = "array"
+		schema["items"] = jsonSchema(*ts.UnitType)
+	case VTMap:
+		jsonType = "object"
+		schema["additionalProperties"] = jsonSchema(*ts.UnitType)
+	case VTRegexp:
+		jsonType = "string"
+		schema["format"] = "regex"
+	case VTString:
+		jsonType = "string"
+	default:
+		panic(fmt.Sprintf("can't create JSON schema for value type %v", ts.BaseType))
+	}
+	schema["type"] = []string{jsonType, "null"}
+	return schema
+}
+
+// SchemaMismatch describes a part of a JSON document not matching a type signature
+type SchemaMismatch struct {
+	// JSON pointer (RFC 6901) to the mismatching part of the document ("" for the whole document)
+	Pointer string
+	// The expected type
+	Expected TypeSignature
+	// A description of the mismatch
+	Message string
+}
+
+func (sm SchemaMismatch) String() string {
+	return fmt.Sprintf("%s: %s", sm.Pointer, sm.Message)
+}
+
+// ValidateJSON checks that a decoded JSON document (e.g. the result of json.Unmarshal into an interface{}) matches
+// a type signature. All mismatches are returned (sorted by JSON pointer for map entries). If the document matches
+// the type signature nil is returned.
+// JSON numbers (float64 or json.Number) must be integral to match an integer. Strings must be valid regular
+// expressions to match a regexp and valid decimals (see ParseDecimal()) to match a decimal. Any JSON value
+// matches VTAny. As for JSONSchema() null matches any type.
+// A matching document may be converted to a value of the type signature using NewExprValueFromJSON().
+func ValidateJSON(ts TypeSignature, doc interface{}) []SchemaMismatch {
+	var mismatches []SchemaMismatch
+	validateJSON(ts, doc, "", &mismatches)
+	return mismatches
+}
+
+func validateJSON(ts TypeSignature, doc interface{}, pointer string, mismatches *[]SchemaMismatch) {
+	if doc == nil {
+		return
+	}
+	mismatch := func(format string, args ...interface{}) {
+		*mismatches = append(*mismatches, SchemaMismatch{
+			Pointer:  pointer,
+			Expected: ts,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	switch ts.BaseType {
+	case VTAny:
+		// Any JSON value matches
+	case VTBoolean:
+		if _, ok := doc.(bool); !ok {
+			mismatch("expected boolean (got %s)", jsonTypeOf(doc))
+		}
+	case VTDecimal:
+		str, ok := doc.(string)
+		if !ok {
+			mismatch("expected string (got %s)", jsonTypeOf(doc))
+			return
+		}
+		if _, err := ParseDecimal(str); err != nil {
+			mismatch("%v", err)
+		}
+	case VTInteger:
+		if !jsonInteger(doc) {
+			mismatch("expected integer (got %s)", jsonTypeOf(doc))
+		}
+	case VTList:
+		list, ok := doc.([]interface{})
+		if !ok {
+			mismatch("expected array (got %s)", jsonTypeOf(doc))
+			return
+		}
+		for i, item := range list {
+			validateJSON(*ts.UnitType, item, pointer+"/"+strconv.Itoa(i), mismatches)
+		}
+	case VTMap:
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			mismatch("expected object (got %s)", jsonTypeOf(doc))
+			return
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			validateJSON(*ts.UnitType, object[key], pointer+"/"+escapeJSONPointer(key), mismatches)
+		}
+	case VTRegexp:
+		str, ok := doc.(string)
+		if !ok {
+			mismatch("expected string (got %s)", jsonTypeOf(doc))
+			return
+		}
+		if _, err := regexp.Compile(str); err != nil {
+			mismatch("invalid regular expression: %v", err)
+		}
+	case VTString:
+		if _, ok := doc.(string); !ok {
+			mismatch("expected string (got %s)", jsonTypeOf(doc))
+		}
+	default:
+		mismatch("unsupported type %v", ts)
+	}
+}
+
+// NewExprValueFromJSON creates a value of the type signature from a decoded JSON document (e.g. the result of
+// json.Unmarshal into an interface{}). The document is encoded as described by JSONSchema(). That is decimals and
+// regexps are strings and null is a nil value of the type. The value of an any value is created using
+// NewExprValueFromInterface(). An error is returned if the document doesn't match the type signature (see
+// ValidateJSON()).
+func NewExprValueFromJSON(ts TypeSignature, doc interface{}) (Value, error) {
+	if mismatches := ValidateJSON(ts, doc); mismatches != nil {
+		return NewNilExprValue(ts), fmt.Errorf("document doesn't match type %s: %v", ts.Text(), mismatches)
+	}
+	return valueFromJSON(ts, doc)
+}
+
+// valueFromJSON creates a value of the type signature from a decoded JSON document matching the type signature
+func valueFromJSON(ts TypeSignature, doc interface{}) (Value, error) {
+	if doc == nil {
+		return NewNilExprValue(ts), nil
+	}
+	switch ts.BaseType {
+	case VTAny:
+		value, err := NewExprValueFromInterface(doc)
+		if err != nil {
+			return NewNilExprValue(ts), err
+		}
+		return NewExprValueAny(value), nil
+	case VTBoolean:
+		return NewExprValueBoolean(doc.(bool)), nil
+	case VTDecimal:
+		d, err := ParseDecimal(doc.(string))
+		if err != nil {
+			return NewNilExprValue(ts), err
+		}
+		return NewExprValueDecimal(d), nil
+	case VTInteger:
+		return NewExprValueFromInterface(doc)
+	case VTList:
+		list := make([]Value, 0, len(doc.([]interface{})))
+		for _, item := range doc.([]interface{}) {
+			value, err := valueFromJSON(*ts.UnitType, item)
+			if err != nil {
+				return NewNilExprValue(ts), err
+			}
+			list = append(list, value)
+		}
+		return NewExprValueList(*ts.UnitType, list), nil
+	case VTMap:
+		valueMap := make(map[string]Value, len(doc.(map[string]interface{})))
+		for key, item := range doc.(map[string]interface{}) {
+			value, err := valueFromJSON(*ts.UnitType, item)
+			if err != nil {
+				return NewNilExprValue(ts), err
+			}
+			valueMap[key] = value
+		}
+		return NewExprValueMap(*ts.UnitType, valueMap), nil
+	case VTRegexp:
+		return NewExprValueRegexp(doc.(string))
+	case VTString:
+		return NewExprValueString(doc.(string)), nil
+	}
+	return NewNilExprValue(ts), fmt.Errorf("unsupported type %v", ts)
+}
+
+// jsonInteger returns true if a decoded JSON value is an integral number
+func jsonInteger(doc interface{}) bool {
+	switch v := doc.(type) {
+	case float64, json.Number:
+		// Same rules as for converting the number to an integer value
+		_, err := NewExprValueFromInterface(v)
+		return err == nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
+	}
+	return false
+}
+
+// jsonTypeOf returns the JSON type name of a decoded JSON value
+func jsonTypeOf(doc interface{}) string {
+	switch doc.(type) {
+	case bool:
+		return "boolean"
+	case float64, json.Number, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", doc)
+}
+
+// escapeJSONPointer escapes a reference token of a JSON pointer (RFC 6901)
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
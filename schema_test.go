@@ -0,0 +1,201 @@
+package goexpr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		ts     TypeSignature
+		schema string
+	}{
+		{"boolean", NewScalarTypeSignature(VTBoolean),
+			`{"$schema":"http://json-schema.org/draft-07/schema#","type":["boolean","null"]}`},
+		{"regexp", NewScalarTypeSignature(VTRegexp),
+			`{"$schema":"http://json-schema.org/draft-07/schema#","format":"regex","type":["string","null"]}`},
+		{"listOfIntegers", NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTInteger)),
+			`{"$schema":"http://json-schema.org/draft-07/schema#","items":{"type":["integer","null"]},` +
+				`"type":["array","null"]}`},
+		{"decimal", NewScalarTypeSignature(VTDecimal),
+			`{"$schema":"http://json-schema.org/draft-07/schema#","pattern":"^[+-]?[0-9]+(\\.[0-9]+)?$",` +
+				`"type":["string","null"]}`},
+		{"listOfAny", NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTAny)),
+			`{"$schema":"http://json-schema.org/draft-07/schema#","items":{},"type":["array","null"]}`},
+		{"mapOfLists", NewCompositeTypeSignature(VTMap,
+			NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString))),
+			`{"$schema":"http://json-schema.org/draft-07/schema#","additionalProperties":` +
+				`{"items":{"type":["string","null"]},"type":["array","null"]},"type":["object","null"]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := json.Marshal(JSONSchema(test.ts))
+			if err != nil {
+				t.Errorf("unexpected marshal error: %v", err)
+				return
+			}
+			if string(schema) != test.schema {
+				t.Errorf("wrong schema.\nactual:   %s\nexpected: %s", schema, test.schema)
+			}
+		})
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	tsString := NewScalarTypeSignature(VTString)
+	tests := []struct {
+		name       string
+		ts         TypeSignature
+		doc        string
+		mismatches string
+	}{
+		{"string", tsString, `"foo"`, `[]`},
+		{"null", tsString, `null`, `[]`},
+		{"stringMismatch", tsString, `1`, `[: expected string (got number)]`},
+		{"integer", tsInteger, `42`, `[]`},
+		{"integerFraction", tsInteger, `4.2`, `[: expected integer (got number)]`},
+		{"boolean", NewScalarTypeSignature(VTBoolean), `"true"`, `[: expected boolean (got string)]`},
+		{"regexp", NewScalarTypeSignature(VTRegexp), `"[a-z]+"`, `[]`},
+		{"regexpInvalid", NewScalarTypeSignature(VTRegexp), `"("`,
+			"[: invalid regular expression: error parsing regexp: missing closing ): `(`]"},
+		{"decimal", NewScalarTypeSignature(VTDecimal), `"-12.50"`, `[]`},
+		{"decimalNumber", NewScalarTypeSignature(VTDecimal), `12.5`, `[: expected string (got number)]`},
+		{"decimalInvalid", NewScalarTypeSignature(VTDecimal), `"12,50"`, `[: invalid decimal "12,50"]`},
+		{"list", NewCompositeTypeSignature(VTList, tsInteger), `[1, null, 3]`, `[]`},
+		{"listMismatches", NewCompositeTypeSignature(VTList, tsInteger), `[1, "two", true]`,
+			`[/1: expected integer (got string) /2: expected integer (got boolean)]`},
+		{"listOfAny", NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTAny)), `["a", 1, [true]]`, `[]`},
+		{"listNotArray", NewCompositeTypeSignature(VTList, tsInteger), `{"a": 1}`,
+			`[: expected array (got object)]`},
+		{"nested", NewCompositeTypeSignature(VTMap, NewCompositeTypeSignature(VTList, tsString)),
+			`{"roles": ["admin", 1], "a/b": "x", "c~d": [false]}`,
+			`[/a~1b: expected array (got string) /c~0d/0: expected string (got boolean) ` +
+				`/roles/1: expected string (got number)]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var doc interface{}
+			err := json.Unmarshal([]byte(test.doc), &doc)
+			if err != nil {
+				t.Errorf("unexpected unmarshal error: %v", err)
+				return
+			}
+			mismatches := fmt.Sprint(ValidateJSON(test.ts, doc))
+			if mismatches != test.mismatches {
+				t.Errorf("wrong mismatches.\nactual:   %s\nexpected: %s", mismatches, test.mismatches)
+			}
+		})
+	}
+}
+
+func TestValidateJSON_Number(t *testing.T) {
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	if mismatches := ValidateJSON(tsInteger, json.Number("12")); mismatches != nil {
+		t.Errorf("unexpected mismatches: %v", mismatches)
+	}
+	if mismatches := ValidateJSON(tsInteger, json.Number("1.5")); len(mismatches) != 1 {
+		t.Errorf("expected one mismatch (got %v)", mismatches)
+	}
+}
+
+func TestValidateJSON_ToValue(t *testing.T) {
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	tsIntegerList := NewCompositeTypeSignature(VTList, tsInteger)
+	tests := []struct {
+		name  string
+		ts    TypeSignature
+		doc   string
+		value Value
+	}{
+		{"integer", tsInteger, `42`, NewExprValueInteger(42)},
+		{"list", tsIntegerList, `[1, 2]`,
+			NewExprValueList(tsInteger, []Value{NewExprValueInteger(1), NewExprValueInteger(2)})},
+		{"map", NewCompositeTypeSignature(VTMap, tsInteger), `{"a": 1, "b": null}`,
+			NewExprValueMap(tsInteger, map[string]Value{"a": NewExprValueInteger(1), "b": EvNilInteger})},
+		{"mapOfLists", NewCompositeTypeSignature(VTMap, tsIntegerList), `{"a": [1e2]}`,
+			NewExprValueMap(tsIntegerList, map[string]Value{
+				"a": NewExprValueList(tsInteger, []Value{NewExprValueInteger(100)})})},
+		{"listOfAny", NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTAny)), `["a", 1]`,
+			NewExprValueList(NewScalarTypeSignature(VTAny), []Value{
+				NewExprValueAny(NewExprValueString("a")), NewExprValueAny(NewExprValueInteger(1))})},
+	}
+	for _, test := range tests {
+		for _, useNumber := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/useNumber=%v", test.name, useNumber), func(t *testing.T) {
+				var doc interface{}
+				dec := json.NewDecoder(strings.NewReader(test.doc))
+				if useNumber {
+					dec.UseNumber()
+				}
+				err := dec.Decode(&doc)
+				if err != nil {
+					t.Errorf("unexpected decode error: %v", err)
+					return
+				}
+				if mismatches := ValidateJSON(test.ts, doc); mismatches != nil {
+					t.Errorf("unexpected mismatches: %v", mismatches)
+					return
+				}
+				value, err := NewExprValueFromInterface(doc)
+				if err != nil {
+					t.Errorf("unexpected conversion error: %v", err)
+					return
+				}
+				if !value.Equal(test.value) {
+					t.Errorf("wrong value.\nactual:   %v\nexpected: %v", value, test.value)
+				}
+			})
+		}
+	}
+}
+
+func TestNewExprValueFromJSON(t *testing.T) {
+	tsInteger := NewScalarTypeSignature(VTInteger)
+	tsAny := NewScalarTypeSignature(VTAny)
+	tsDecimal := NewScalarTypeSignature(VTDecimal)
+	tests := []struct {
+		name  string
+		ts    TypeSignature
+		doc   string
+		value Value
+	}{
+		{"integer", tsInteger, `42`, NewExprValueInteger(42)},
+		{"null", tsInteger, `null`, EvNilInteger},
+		{"decimal", tsDecimal, `"12.50"`, NewExprValueDecimal(ParseDecimalMust("12.50"))},
+		{"regexp", NewScalarTypeSignature(VTRegexp), `"[a-z]+"`, NewExprValueRegexpMust("[a-z]+")},
+		{"emptyList", NewCompositeTypeSignature(VTList, tsInteger), `[]`, NewExprValueList(tsInteger, []Value{})},
+		{"listOfAny", NewCompositeTypeSignature(VTList, tsAny), `["a", "b"]`, NewExprValueList(tsAny, []Value{
+			NewExprValueAny(NewExprValueString("a")), NewExprValueAny(NewExprValueString("b"))})},
+		{"mapOfDecimals", NewCompositeTypeSignature(VTMap, tsDecimal), `{"a": "1.5", "b": null}`,
+			NewExprValueMap(tsDecimal, map[string]Value{
+				"a": NewExprValueDecimal(ParseDecimalMust("1.5")), "b": NewNilExprValue(tsDecimal)})},
+		{"listOfLists", NewCompositeTypeSignature(VTList, NewCompositeTypeSignature(VTList, tsInteger)), `[[1], []]`,
+			NewExprValueList(NewCompositeTypeSignature(VTList, tsInteger), []Value{
+				NewExprValueList(tsInteger, []Value{NewExprValueInteger(1)}), NewExprValueList(tsInteger, []Value{})})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var doc interface{}
+			err := json.Unmarshal([]byte(test.doc), &doc)
+			if err != nil {
+				t.Errorf("unexpected unmarshal error: %v", err)
+				return
+			}
+			value, err := NewExprValueFromJSON(test.ts, doc)
+			if err != nil {
+				t.Errorf("unexpected conversion error: %v", err)
+				return
+			}
+			if !value.Type.Equal(test.ts) || !value.Equal(test.value) {
+				t.Errorf("wrong value.\nactual:   %v (%v)\nexpected: %v (%v)", value, value.Type, test.value, test.ts)
+			}
+		})
+	}
+	if _, err := NewExprValueFromJSON(tsInteger, "42"); err == nil {
+		t.Errorf("expected error for mismatching document")
+	}
+}
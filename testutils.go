@@ -23,7 +23,7 @@ func (rc testRequestContext) Reference(key interface{}, ts TypeSignature) (Value
 	}
 	v, err := NewExprValueFromString(ts, vS)
 	if err != nil {
-		return NewNilExprValue(ts), fmt.Errorf("can't convert value %v to type %v: %v", v, ts, err)
+		return NewNilExprValue(ts), fmt.Errorf("can't convert value %v to type %v: %w", v, ts, err)
 	}
 	return v, nil
 }
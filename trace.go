@@ -0,0 +1,92 @@
+package goexpr
+
+import (
+	"sync"
+)
+
+// evalHook is called when an instrumented expression is evaluated. The hook is called with the original (not
+// instrumented) expression and a function evaluating the instrumented expression. The hook must return the
+// result of the evaluation function.
+type evalHook func(orig Expression, eval func() (Value, error)) (Value, error)
+
+// exprInstrumented wraps an expression and calls a hook each time the expression is evaluated.
+type exprInstrumented struct {
+	// The instrumented copy of the original expression (with instrumented sub-expressions)
+	Expression
+	// The original expression
+	orig Expression
+	hook evalHook
+}
+
+func (op *exprInstrumented) Evaluate(recCtx RequestContext) (Value, error) {
+	return op.hook(op.orig, func() (Value, error) {
+		return op.Expression.Evaluate(recCtx)
+	})
+}
+
+func (op *exprInstrumented) WithChildren(children []Expression) (Expression, error) {
+	expr, err := op.Expression.WithChildren(children)
+	if err != nil {
+		return nil, err
+	}
+	return &exprInstrumented{Expression: expr, orig: op.orig, hook: op.hook}, nil
+}
+
+// instrument returns a copy of the expression tree where each expression is wrapped by an instrumented expression
+// calling the specified hook. The original expression tree is not changed.
+func instrument(expr Expression, hook evalHook) (Expression, error) {
+	inst := expr
+	children := expr.Children()
+	if len(children) > 0 {
+		instChildren := make([]Expression, len(children))
+		for i, child := range children {
+			instChild, err := instrument(child, hook)
+			if err != nil {
+				return nil, err
+			}
+			instChildren[i] = instChild
+		}
+		var err error
+		inst, err = expr.WithChildren(instChildren)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &exprInstrumented{Expression: inst, orig: expr, hook: hook}, nil
+}
+
+// EvaluationTrace records the resulting values of all expressions evaluated in an expression tree.
+// An expression tree is traced by evaluating the instrumented expression returned by Instrument().
+type EvaluationTrace struct {
+	mu     sync.Mutex
+	values map[Expression][]Value
+}
+
+// NewEvaluationTrace creates a new empty evaluation trace.
+func NewEvaluationTrace() *EvaluationTrace {
+	return &EvaluationTrace{values: make(map[Expression][]Value)}
+}
+
+// Instrument returns an instrumented copy of the expression tree. Each time the instrumented expression is
+// evaluated the result of each evaluated expression is recorded in the trace. The values are recorded for the
+// expressions in the original expression tree.
+// Expressions that return an error are not recorded.
+func (et *EvaluationTrace) Instrument(expr Expression) (Expression, error) {
+	return instrument(expr, func(orig Expression, eval func() (Value, error)) (Value, error) {
+		value, err := eval()
+		if err == nil {
+			et.mu.Lock()
+			et.values[orig] = append(et.values[orig], value)
+			et.mu.Unlock()
+		}
+		return value, err
+	})
+}
+
+// Values returns the recorded values for the expression in evaluation order. An expression evaluated multiple times
+// (e.g. in a for loop) has multiple values. If the expression was never evaluated nil is returned.
+func (et *EvaluationTrace) Values(expr Expression) []Value {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+	return et.values[expr]
+}
@@ -0,0 +1,58 @@
+package goexpr
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvaluationTrace(t *testing.T) {
+	l, c := 1, 2
+	loop := NewExprCompareMust(CTEqual, NewExprHeapReference("k1", "k1", l, c),
+		NewExprConstant(NewExprValueString("bar"), l, c), l, c)
+	notEvaluated := NewExprConstant(NewExprValueString("else"), l, c)
+	op := NewExprIf(
+		NewExprFor(
+			NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+				NewExprValueString("foo"),
+				NewExprValueString("bar"),
+			}), l, c),
+			loop, nil, "k1", l, c),
+		NewExprConstant(NewExprValueString("then"), l, c),
+		notEvaluated, l, c)
+
+	trace := NewEvaluationTrace()
+	inst, err := trace.Instrument(op)
+	if err != nil {
+		t.Errorf("unexpected instrument error: %v", err)
+		return
+	}
+	if inst.String() != op.String() {
+		t.Errorf("wrong instrumented expression.\nactual:   %v\nexpected: %v", inst, op)
+	}
+	res, err := inst.Evaluate(newEmptyTestRequestContext())
+	if err != nil {
+		t.Errorf("unexpected evaluation error: %v", err)
+		return
+	}
+	if !res.Equal(NewExprValueString("then")) {
+		t.Errorf("wrong evaluation result: %v", res)
+	}
+
+	tests := []struct {
+		name   string
+		op     Expression
+		values string
+	}{
+		{"root", op, `["then"]`},
+		{"loop", loop, `[false true]`},
+		{"notEvaluated", notEvaluated, `[]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values := fmt.Sprint(trace.Values(test.op))
+			if values != test.values {
+				t.Errorf("wrong traced values.\nactual:   %s\nexpected: %s", values, test.values)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package goexpr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Text returns the textual type syntax for the type signature (e.g. "list<string>" or "map<list<integer>>").
+// The text may be parsed back to the type signature using ParseTypeSignature().
+func (ts TypeSignature) Text() string {
+	if ts.UnitType == nil {
+		return string(ts.BaseType)
+	}
+	return fmt.Sprintf("%s<%s>", ts.BaseType, ts.UnitType.Text())
+}
+
+// ParseTypeSignature parses a type signature from text. Both the textual type syntax (see TypeSignature.Text(),
+// e.g. "map<list<integer>>") and the type signature string form (see TypeSignature.String(), e.g.
+// "{map {list {integer}}}") are supported. Whitespace between the parts of a type is ignored. The type of the
+// nil literal (see TsNil) is parsed from "nil" (or "{nil}").
+// An error is returned if the text isn't a valid type, if a value type is unknown, if a composite type (list or
+// map) lacks a unit type or if a scalar type has a unit type.
+func ParseTypeSignature(text string) (TypeSignature, error) {
+	p := &typeParser{text: text}
+	ts, err := p.parseType()
+	if err != nil {
+		return TypeSignature{}, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.text) {
+		return TypeSignature{}, p.errorf("unexpected %q after type", p.text[p.pos:])
+	}
+	return ts, nil
+}
+
+func ParseTypeSignatureMust(text string) TypeSignature {
+	ts, err := ParseTypeSignature(text)
+	if err != nil {
+		panic(fmt.Sprintf("error parsing type signature: %v", err))
+	}
+	return ts
+}
+
+// typeParser is a recursive descent parser for type signatures
+type typeParser struct {
+	text string
+	pos  int
+}
+
+func (p *typeParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid type %q at position %d: %s", p.text, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *typeParser) skipSpace() {
+	for p.pos < len(p.text) && unicode.IsSpace(rune(p.text[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept skips whitespace and consumes the character if it is next in the text
+func (p *typeParser) accept(ch byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.text) && p.text[p.pos] == ch {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *typeParser) expect(ch byte) error {
+	if !p.accept(ch) {
+		if p.pos >= len(p.text) {
+			return p.errorf("expected %q but got end of text", ch)
+		}
+		return p.errorf("expected %q but got %q", ch, p.text[p.pos])
+	}
+	return nil
+}
+
+// parseType parses "{base [unit]}" or "base[<unit>]"
+func (p *typeParser) parseType() (TypeSignature, error) {
+	if p.accept('{') {
+		vt, err := p.parseValueType()
+		if err != nil {
+			return TypeSignature{}, err
+		}
+		var unit *TypeSignature
+		p.skipSpace()
+		if p.pos < len(p.text) && p.text[p.pos] != '}' {
+			ts, err := p.parseType()
+			if err != nil {
+				return TypeSignature{}, err
+			}
+			unit = &ts
+		}
+		err = p.expect('}')
+		if err != nil {
+			return TypeSignature{}, err
+		}
+		return p.newTypeSignature(vt, unit)
+	}
+	vt, err := p.parseValueType()
+	if err != nil {
+		return TypeSignature{}, err
+	}
+	var unit *TypeSignature
+	if p.accept('<') {
+		ts, err := p.parseType()
+		if err != nil {
+			return TypeSignature{}, err
+		}
+		err = p.expect('>')
+		if err != nil {
+			return TypeSignature{}, err
+		}
+		unit = &ts
+	}
+	return p.newTypeSignature(vt, unit)
+}
+
+func (p *typeParser) parseValueType() (ValueType, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.text) && unicode.IsLetter(rune(p.text[p.pos])) {
+		p.pos++
+	}
+	name := p.text[start:p.pos]
+	if name == "" {
+		if p.pos >= len(p.text) {
+			return "", p.errorf("expected a value type but got end of text")
+		}
+		return "", p.errorf("expected a value type but got %q", p.text[p.pos])
+	}
+	vt := ValueType(name)
+	// The nil type has no value type metadata
+	if vt != VTNil && !VTMetadata.ValidValueType(vt) {
+		p.pos = start
+		return "", p.errorf("unknown value type %q (expected one of %s)", name, strings.Join(validValueTypes(), ", "))
+	}
+	return vt, nil
+}
+
+// newTypeSignature creates a type signature checking that composite types (and only composite types) have a unit
+// type
+func (p *typeParser) newTypeSignature(vt ValueType, unit *TypeSignature) (TypeSignature, error) {
+	scalar := vt == VTNil || VTMetadata.Scalar(vt)
+	if !scalar && unit == nil {
+		return TypeSignature{}, p.errorf("%s requires a unit type (e.g. %s<string>)", vt, vt)
+	}
+	if scalar && unit != nil {
+		return TypeSignature{}, p.errorf("scalar type %s can't have a unit type", vt)
+	}
+	if unit == nil {
+		return NewScalarTypeSignature(vt), nil
+	}
+	return NewCompositeTypeSignature(vt, *unit), nil
+}
+
+// validValueTypes returns the names of the valid value types in sorted order
+func validValueTypes() []string {
+	names := make([]string, 0, len(VTMetadata)+1)
+	names = append(names, string(VTNil))
+	for vt := range VTMetadata {
+		names = append(names, string(vt))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,99 @@
+package goexpr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTypeSignature(t *testing.T) {
+	tsString := NewScalarTypeSignature(VTString)
+	tsIntegerList := NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTInteger))
+	tests := []struct {
+		name string
+		text string
+		ts   TypeSignature
+	}{
+		{"scalar", "string", tsString},
+		{"list", "list<string>", NewCompositeTypeSignature(VTList, tsString)},
+		{"nested", "map<list<integer>>", NewCompositeTypeSignature(VTMap, tsIntegerList)},
+		{"whitespace", " map < list<integer> > ", NewCompositeTypeSignature(VTMap, tsIntegerList)},
+		{"braceScalar", "{string}", tsString},
+		{"braceNested", "{map {list {integer}}}", NewCompositeTypeSignature(VTMap, tsIntegerList)},
+		{"mixed", "{map list<integer>}", NewCompositeTypeSignature(VTMap, tsIntegerList)},
+		{"any", "list<any>", NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTAny))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts, err := ParseTypeSignature(test.text)
+			if err != nil {
+				t.Errorf("unexpected parse error: %v", err)
+				return
+			}
+			if !ts.Equal(test.ts) {
+				t.Errorf("wrong type signature.\nactual:   %v\nexpected: %v", ts, test.ts)
+			}
+		})
+	}
+}
+
+func TestParseTypeSignature_RoundTrip(t *testing.T) {
+	types := []TypeSignature{
+		NewScalarTypeSignature(VTBoolean),
+		NewScalarTypeSignature(VTRegexp),
+		TsNil,
+		NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString)),
+		NewCompositeTypeSignature(VTMap, NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTInteger))),
+	}
+	for _, ts := range types {
+		t.Run(ts.Text(), func(t *testing.T) {
+			for _, text := range []string{ts.Text(), ts.String()} {
+				parsed, err := ParseTypeSignature(text)
+				if err != nil {
+					t.Errorf("unexpected parse error: %v", err)
+					continue
+				}
+				if !parsed.Equal(ts) {
+					t.Errorf("wrong type signature.\nactual:   %v\nexpected: %v", parsed, ts)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTypeSignature_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		msg  string
+	}{
+		{"empty", "", "expected a value type but got end of text"},
+		{"unknown", "list<strnig>", `at position 5: unknown value type "strnig" (expected one of any, boolean, ` +
+			`decimal, integer, list, map, nil, regexp, string)`},
+		{"noUnit", "list", "list requires a unit type"},
+		{"scalarUnit", "string<integer>", "scalar type string can't have a unit type"},
+		{"nilUnit", "nil<string>", "scalar type nil can't have a unit type"},
+		{"unclosed", "list<string", `expected '>' but got end of text`},
+		{"braceUnclosed", "{list {string}", `expected '}' but got end of text`},
+		{"trailing", "string string", `unexpected "string" after type`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseTypeSignature(test.text)
+			if err == nil {
+				t.Errorf("expected parse error")
+				return
+			}
+			if !strings.Contains(err.Error(), test.msg) {
+				t.Errorf("wrong error message.\nactual:   %v\nexpected: %v", err, test.msg)
+			}
+		})
+	}
+}
+
+func TestTypeSignature_Text(t *testing.T) {
+	ts := NewCompositeTypeSignature(VTMap, NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTInteger)))
+	expected := "map<list<integer>>"
+	if ts.Text() != expected {
+		t.Errorf("wrong text.\nactual:   %v\nexpected: %v", ts.Text(), expected)
+	}
+}
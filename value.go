@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/habak67/go-utils"
+	"math"
 	"reflect"
 	"regexp"
 	"sort"
@@ -40,9 +41,15 @@ func init() {
 }
 
 // Typed value. The supported value type are represented as follows.
+// Any
+//   type = any
+//   value = Value (a non-nil value of any other type, see NewExprValueAny())
 // Boolean
 //   type = boolean
 //	 value = bool
+// Decimal
+//   type = decimal
+//   value = Decimal
 // Integer
 //   type = integer
 //   value = int
@@ -88,8 +95,13 @@ func (ev Value) Equal(ev2 Value) bool {
 		return ev.Nil() == ev2.Nil()
 	}
 	switch ev.Type.BaseType {
+	case VTAny:
+		return ev.Value.(Value).Equal(ev2.Value.(Value))
 	case VTBoolean:
 		return ev.Value.(bool) == ev2.Value.(bool)
+	case VTDecimal:
+		// Decimals with different scales may be equal (e.g. 1.5 and 1.50)
+		return ev.Value.(Decimal).Cmp(ev2.Value.(Decimal)) == 0
 	case VTInteger:
 		return ev.Value.(int) == ev2.Value.(int)
 	case VTList:
@@ -129,35 +141,131 @@ func (ev Value) Equal(ev2 Value) bool {
 	}
 }
 
+// Collator compares strings using locale specific rules. It returns a negative value, 0 or a positive value
+// if a is less than, equal to or greater than b respectively. Note that *collate.Collator (golang.org/x/text)
+// implements the interface.
+type Collator interface {
+	CompareString(a, b string) int
+}
+
 // Compare return a negative value if the REL value is less than a specified REL value.
 // It return positive value if the REL value is greater than the specified REL value
 // and it return 0 if the REL value is equal to the specified REL value.
 // The result is returned as an integer rel value.
+// Strings are compared byte-wise, booleans are ordered false < true, decimals are compared by value (regardless
+// of scale) and lists are compared lexicographically
+// (element by element where a nil element is less than a non-nil element and a shorter list is less than a
+// longer list having the shorter list as prefix).
 // If one of the rel values are nil then the result is nil.
 // If the value type is not comparable a panic is raised.
 func (ev Value) Compare(Ev2 Value) Value {
+	return ev.CompareCollated(Ev2, nil)
+}
+
+// CompareCollated compares two values in the same way as Compare() but compares strings (including strings in
+// lists) using the specified collator. If the collator is nil strings are compared byte-wise.
+func (ev Value) CompareCollated(Ev2 Value, collator Collator) Value {
 	if !ev.Type.Equal(Ev2.Type) {
 		panic(fmt.Sprintf("incompatible values to compare (%v != %v)", ev.Type, Ev2.Type))
 	}
+	if !VTMetadata.ComparableType(ev.Type) {
+		panic(fmt.Sprintf("value type %v is not comparable", ev.Type.BaseType))
+	}
+	if ev.Nil() || Ev2.Nil() {
+		return EvNilInteger
+	}
+	return NewExprValueInteger(compareValues(ev, Ev2, collator))
+}
+
+// compareValues compares two non-nil comparable values of the same type
+func compareValues(ev, ev2 Value, collator Collator) int {
 	switch ev.Type.BaseType {
+	case VTBoolean:
+		b1, b2 := ev.Value.(bool), ev2.Value.(bool)
+		switch {
+		case b1 == b2:
+			return 0
+		case b2:
+			return -1
+		default:
+			return 1
+		}
+	case VTDecimal:
+		return ev.Value.(Decimal).Cmp(ev2.Value.(Decimal))
 	case VTInteger:
-		if ev.Nil() || Ev2.Nil() {
-			return NewNilExprValue(ev.Type)
+		// Don't subtract the integers as it may overflow
+		i1, i2 := ev.Value.(int), ev2.Value.(int)
+		switch {
+		case i1 < i2:
+			return -1
+		case i1 > i2:
+			return 1
+		default:
+			return 0
+		}
+	case VTList:
+		l1, l2 := ev.Value.([]Value), ev2.Value.([]Value)
+		for i := 0; i < len(l1) && i < len(l2); i++ {
+			switch {
+			case l1[i].Nil() && l2[i].Nil():
+				continue
+			case l1[i].Nil():
+				return -1
+			case l2[i].Nil():
+				return 1
+			}
+			cmp := compareValues(l1[i], l2[i], collator)
+			if cmp != 0 {
+				return cmp
+			}
 		}
-		return NewExprValueInteger(ev.Value.(int) - Ev2.Value.(int))
+		return len(l1) - len(l2)
+	case VTString:
+		if collator != nil {
+			return collator.CompareString(ev.Value.(string), ev2.Value.(string))
+		}
+		return strings.Compare(ev.Value.(string), ev2.Value.(string))
 	default:
 		panic(fmt.Sprintf("value type %v is not comparable", ev.Type.BaseType))
 	}
 }
 
+// sortedKeys returns the keys of a map value sorted in ascending order
+func sortedKeys(valueMap map[string]Value) []string {
+	keys := make([]string, 0, len(valueMap))
+	for key := range valueMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// iterationValues returns the values to iterate over for an iterable value. For a list it is the list values and
+// for a map it is the map values in key order (see sortedKeys()).
+func (ev Value) iterationValues() []Value {
+	if ev.Type.BaseType != VTMap {
+		return ev.Value.([]Value)
+	}
+	valueMap := ev.Value.(map[string]Value)
+	values := make([]Value, 0, len(valueMap))
+	for _, key := range sortedKeys(valueMap) {
+		values = append(values, valueMap[key])
+	}
+	return values
+}
+
 // String return a compact string representation of the REL value
 func (ev Value) String() string {
 	if ev.Nil() {
 		return "<<nil>>"
 	}
 	switch ev.Type.BaseType {
+	case VTAny:
+		return ev.Value.(Value).String()
 	case VTBoolean:
 		return strconv.FormatBool(ev.Value.(bool))
+	case VTDecimal:
+		return ev.Value.(Decimal).String()
 	case VTInteger:
 		return strconv.FormatInt(int64(ev.Value.(int)), 10)
 	case VTList:
@@ -179,13 +287,8 @@ func (ev Value) String() string {
 
 		// To get a consistent map string we "sort" the map by key
 		valueMap := ev.Value.(map[string]Value)
-		keys := make([]string, 0, len(valueMap))
-		for key := range valueMap {
-			keys = append(keys, key)
-		}
-		sort.Strings(keys)
 		first := true
-		for _, key := range keys {
+		for _, key := range sortedKeys(valueMap) {
 			if !first {
 				sb.WriteString(",")
 			}
@@ -241,7 +344,47 @@ func (ev Value) Assign(_ interface{}, _ Value) {
 	panic(fmt.Sprintf("value type %v is not assignable", ev.Type.BaseType))
 }
 
-// Reference returns the sub-value for the specified key
+// Slice is a reference key selecting a part of a list or a string value (e.g. items[1:3]). The slice starts at
+// Start and ends at, but does not include, End. A negative index counts from the end of the value. If OpenEnd is
+// true End is ignored and the slice extends to the end of the value.
+type Slice struct {
+	Start   int
+	End     int
+	OpenEnd bool
+}
+
+func (s Slice) String() string {
+	if s.OpenEnd {
+		return fmt.Sprintf("%d:", s.Start)
+	}
+	return fmt.Sprintf("%d:%d", s.Start, s.End)
+}
+
+// bounds returns the slice start and end adjusted to a value of the specified length
+func (s Slice) bounds(length int) (int, int) {
+	start, end := s.Start, s.End
+	if s.OpenEnd {
+		end = length
+	}
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	start, end = clampIndex(start, length), clampIndex(end, length)
+	if start > end {
+		start = end
+	}
+	return start, end
+}
+
+// Reference returns the sub-value for the specified key. The supported keys are
+// map: string (the value for the key)
+// list: int (the value at the index) or Slice (a list with the values in the slice)
+// string: int (the character at the index) or Slice (the characters in the slice)
+// A negative index counts from the end of the value. If an index is out of range a nil value is returned.
+// Slice indexes out of range are adjusted to the closest valid index.
 func (ev Value) Reference(key interface{}) Value {
 	switch ev.Type.BaseType {
 	case VTMap:
@@ -249,9 +392,45 @@ func (ev Value) Reference(key interface{}) Value {
 		keyS := key.(string)
 		m := ev.Value.(map[string]Value)
 		return m[keyS]
+	case VTList:
+		list := ev.Value.([]Value)
+		switch k := key.(type) {
+		case int:
+			index, ok := referenceIndex(k, len(list))
+			if !ok {
+				return NewNilExprValue(*ev.Type.UnitType)
+			}
+			return list[index]
+		case Slice:
+			start, end := k.bounds(len(list))
+			return NewExprValueList(*ev.Type.UnitType, list[start:end])
+		}
+	case VTString:
+		runes := []rune(ev.Value.(string))
+		switch k := key.(type) {
+		case int:
+			index, ok := referenceIndex(k, len(runes))
+			if !ok {
+				return EvNilString
+			}
+			return NewExprValueString(string(runes[index]))
+		case Slice:
+			start, end := k.bounds(len(runes))
+			return NewExprValueString(string(runes[start:end]))
+		}
 	default:
 		panic(fmt.Sprintf("value type %v is not referable", ev.Type.BaseType))
 	}
+	panic(fmt.Sprintf("invalid key %v (%T) referencing value type %v", key, key, ev.Type.BaseType))
+}
+
+// referenceIndex returns the index adjusted for negative indexes (counting from the end). If the index is out of
+// range false is returned.
+func referenceIndex(index, length int) (int, bool) {
+	if index < 0 {
+		index += length
+	}
+	return index, index >= 0 && index < length
 }
 
 func (ev *Value) UnmarshalJSON(data []byte) error {
@@ -269,6 +448,13 @@ func (ev *Value) UnmarshalJSON(data []byte) error {
 	}
 	// Do a typed value dependent unmarshalling depending on the value type
 	switch ev.Type.BaseType {
+	case VTAny:
+		var v Value
+		err := json.Unmarshal(ev1.Value, &v)
+		if err != nil {
+			return err
+		}
+		ev.Value = v
 	case VTBoolean:
 		var v bool
 		err := json.Unmarshal(ev1.Value, &v)
@@ -276,6 +462,13 @@ func (ev *Value) UnmarshalJSON(data []byte) error {
 			return err
 		}
 		ev.Value = v
+	case VTDecimal:
+		var v Decimal
+		err := json.Unmarshal(ev1.Value, &v)
+		if err != nil {
+			return err
+		}
+		ev.Value = v
 	case VTInteger:
 		var v int
 		err := json.Unmarshal(ev1.Value, &v)
@@ -325,7 +518,9 @@ func (ev *Value) UnmarshalJSON(data []byte) error {
 // NewExprValue creates a new expression value from a type signature and a go value. The created expression value
 // is depending of the type signature. Note that the go value must match the specified type signature otherwise an
 // error is returned.
+// VTAny => Value or any go value supported by NewExprValueFromInterface()
 // VTBoolean => bool
+// VTDecimal => Decimal or int (including intX and uintX)
 // VTInteger => int (including intX and uintX)
 // VTList => []Value - a slice of expression values where the type signature unit type specifies the type of the values.
 // VTMap => map[string]Value - a map with string keys and where the type signature sub type specifies the type for the values.
@@ -336,17 +531,37 @@ func NewExprValue(ts TypeSignature, value interface{}) (Value, error) {
 	if value == nil {
 		return NewNilExprValue(ts), nil
 	}
+	if ts.IsValueType(VTAny) {
+		v, ok := value.(Value)
+		if !ok {
+			var err error
+			v, err = NewExprValueFromInterface(value)
+			if err != nil {
+				return EvNil, err
+			}
+		}
+		return NewExprValueAny(v), nil
+	}
 	switch v := value.(type) {
 	case bool:
 		if !ts.IsValueType(VTBoolean) {
 			return EvNil, fmt.Errorf("value %v is not a boolean", value)
 		}
 		return NewExprValueBoolean(v), nil
+	case Decimal:
+		if !ts.IsValueType(VTDecimal) {
+			return EvNil, fmt.Errorf("value %v is not a decimal", value)
+		}
+		return NewExprValueDecimal(v), nil
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-		if !ts.IsValueType(VTInteger) {
+		switch ts.BaseType {
+		case VTDecimal:
+			return NewExprValueDecimal(NewDecimalFromInt(utils.NewIntMust(v))), nil
+		case VTInteger:
+			return NewExprValueInteger(utils.NewIntMust(v)), nil
+		default:
 			return EvNil, fmt.Errorf("value %v is not an integer", value)
 		}
-		return NewExprValueInteger(utils.NewIntMust(v)), nil
 	case []Value:
 		if !ts.IsValueType(VTList) {
 			return EvNil, fmt.Errorf("value %v is not a list", value)
@@ -385,11 +600,45 @@ func NewExprValueMust(ts TypeSignature, value interface{}) Value {
 	return ev
 }
 
+// unifyValues returns the common type of a set of values (e.g. the values of a list) and converts the values to
+// the common type. Untyped nil values (EvNil) get the type of the other values. If the (non-nil) values have
+// different types the common type is VTAny and all values are converted to any values. If there are no non-nil
+// values the common type is TsDefault.
+func unifyValues(values []Value) TypeSignature {
+	var unitType TypeSignature
+	for _, value := range values {
+		if value.Type.Equal(TsNil) {
+			continue
+		}
+		if !unitType.Empty() && !unitType.Equal(value.Type) {
+			unitType = NewScalarTypeSignature(VTAny)
+			break
+		}
+		unitType = value.Type
+	}
+	if unitType.Empty() {
+		unitType = TsDefault
+	}
+	for i, value := range values {
+		switch {
+		case value.Type.Equal(TsNil):
+			values[i] = NewNilExprValue(unitType)
+		case unitType.IsValueType(VTAny):
+			values[i] = NewExprValueAny(value)
+		}
+	}
+	return unitType
+}
+
 // NewExprValueFromInterface creates a new expression value from a go value. The created expression value is
 // depending on the type of go value. If the go value type is unsupported an error is returned.
 // The supported go value types is specified in NewExprValue().
 // Note that a list must be of type []interface{}.
 // Note that a map must be of type map[string]interface{}.
+// Numbers decoded from JSON (float64 or json.Number) are integers. An error is returned if such a number isn't
+// integral (or is out of range for an integer).
+// The unit type of a list (or map) is the type of its (non-nil) values. If the values have different types the
+// unit type is VTAny (see unifyValues()).
 func NewExprValueFromInterface(value interface{}) (Value, error) {
 	if value == nil {
 		return EvNil, nil
@@ -397,48 +646,51 @@ func NewExprValueFromInterface(value interface{}) (Value, error) {
 	switch v := value.(type) {
 	case bool:
 		return NewExprValue(NewScalarTypeSignature(VTBoolean), v)
+	case Decimal:
+		return NewExprValueDecimal(v), nil
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
 		return NewExprValue(NewScalarTypeSignature(VTInteger), v)
+	case float64:
+		return newExprValueIntegerFromFloat(v)
+	case json.Number:
+		i, err := v.Int64()
+		if err == nil {
+			return NewExprValueInteger(int(i)), nil
+		}
+		// The number may still be integral (e.g. 1.0 or 1e3)
+		f, err := v.Float64()
+		if err != nil {
+			return EvNil, fmt.Errorf("can't convert number %v to an integer: %v", v, err)
+		}
+		return newExprValueIntegerFromFloat(f)
 	case []interface{}:
 		// Recursively create a slice of expression values from the go slice values
-		slice := make([]Value, 0)
-		var unitType TypeSignature
+		slice := make([]Value, 0, len(v))
 		for _, subValue := range v {
 			exprValue, err := NewExprValueFromInterface(subValue)
 			if err != nil {
 				return EvNil, fmt.Errorf("error generating expression value from slice value %v: %v", subValue, err)
 			}
-			// Make sure the slice values are of the same type
-			if !unitType.Empty() && !unitType.Equal(exprValue.Type) {
-				return EvNil, fmt.Errorf("slice with different value types (%v(%v) != %v)", subValue, exprValue.Type, unitType)
-			}
-			unitType = exprValue.Type
 			slice = append(slice, exprValue)
 		}
-		// If empty slice we assume a slice of default value type
-		if unitType.Empty() {
-			unitType = TsDefault
-		}
+		unitType := unifyValues(slice)
 		return NewExprValue(NewCompositeTypeSignature(VTList, unitType), slice)
 	case map[string]interface{}: // We only support string keys
 		// Recursively create map expression values from the go map values
 		mp := make(map[string]Value)
-		var unitType TypeSignature
+		keys := make([]string, 0, len(v))
+		values := make([]Value, 0, len(v))
 		for key, subValue := range v {
 			exprValue, err := NewExprValueFromInterface(subValue)
 			if err != nil {
 				return EvNil, fmt.Errorf("error generating expression value from map value %v: %v", subValue, err)
 			}
-			// Make sure the map values are of the same type
-			if !unitType.Empty() && !unitType.Equal(exprValue.Type) {
-				return EvNil, fmt.Errorf("map with different value types (%v(%v) != %v)", subValue, exprValue.Type, unitType)
-			}
-			unitType = exprValue.Type
-			mp[key] = exprValue
+			keys = append(keys, key)
+			values = append(values, exprValue)
 		}
-		// If empty map we assume a slice of default value type
-		if unitType.Empty() {
-			unitType = TsDefault
+		unitType := unifyValues(values)
+		for i, key := range keys {
+			mp[key] = values[i]
 		}
 		return NewExprValue(NewCompositeTypeSignature(VTMap, unitType), mp)
 	case string:
@@ -463,28 +715,67 @@ func NewExprValueFromInterface(value interface{}) (Value, error) {
 	return EvNil, fmt.Errorf("can't convert go value (%v) to an expression value", value)
 }
 
+// newExprValueIntegerFromFloat creates an integer value from an integral float (e.g. a number decoded from JSON).
+// An error is returned if the float has a fraction or is out of range for an integer.
+func newExprValueIntegerFromFloat(f float64) (Value, error) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return EvNil, fmt.Errorf("can't convert number %v to an integer", f)
+	}
+	return NewExprValueInteger(int(f)), nil
+}
+
+// NewExprValueFromString creates a new expression value of the specified type from a string. If the string can't
+// be converted to the type a ConversionError is returned.
 func NewExprValueFromString(ts TypeSignature, value string) (Value, error) {
 	switch ts.BaseType {
 	case VTBoolean:
 		b, err := strconv.ParseBool(value)
 		if err != nil {
-			return NewNilExprValue(ts), err
+			return NewNilExprValue(ts), &ConversionError{Err: err}
 		}
 		return NewExprValueBoolean(b), nil
+	case VTDecimal:
+		d, err := ParseDecimal(value)
+		if err != nil {
+			return NewNilExprValue(ts), &ConversionError{Err: err}
+		}
+		return NewExprValueDecimal(d), nil
 	case VTInteger:
 		i, err := strconv.Atoi(value)
 		if err != nil {
-			return NewNilExprValue(ts), err
+			return NewNilExprValue(ts), &ConversionError{Err: err}
 		}
 		return NewExprValueInteger(i), nil
+	case VTAny:
+		return NewExprValueAny(NewExprValueString(value)), nil
 	case VTNil:
 		return EvNil, nil
 	case VTRegexp:
-		return NewExprValueRegexp(value)
+		re, err := NewExprValueRegexp(value)
+		if err != nil {
+			return re, &ConversionError{Err: err}
+		}
+		return re, nil
 	case VTString:
 		return NewExprValueString(value), nil
 	}
-	return NewNilExprValue(ts), fmt.Errorf("can't create rel value of type %v from string", ts.BaseType)
+	return NewNilExprValue(ts), &ConversionError{Err: fmt.Errorf("can't create rel value of type %v from string",
+		ts.BaseType)}
+}
+
+// NewExprValueAny creates an any value holding the specified value. If the value already is an any value it is
+// returned as is. If the value is nil a nil any value is returned.
+func NewExprValueAny(value Value) Value {
+	if value.Type.IsValueType(VTAny) {
+		return value
+	}
+	if value.Nil() {
+		return NewNilExprValue(NewScalarTypeSignature(VTAny))
+	}
+	return Value{
+		Type:  NewScalarTypeSignature(VTAny),
+		Value: value,
+	}
 }
 
 func NewExprValueBoolean(value bool) Value {
@@ -494,6 +785,13 @@ func NewExprValueBoolean(value bool) Value {
 	}
 }
 
+func NewExprValueDecimal(value Decimal) Value {
+	return Value{
+		Type:  NewScalarTypeSignature(VTDecimal),
+		Value: value,
+	}
+}
+
 func NewExprValueInteger(value int) Value {
 	return Value{
 		Type:  NewScalarTypeSignature(VTInteger),
@@ -554,7 +852,9 @@ func NewExprValueString(value string) Value {
 type ValueType string
 
 const (
+	VTAny     ValueType = "any"
 	VTBoolean ValueType = "boolean"
+	VTDecimal ValueType = "decimal"
 	VTInteger ValueType = "integer"
 	VTList    ValueType = "list"
 	VTMap     ValueType = "map"
@@ -569,6 +869,8 @@ func (vt ValueType) DefaultValue() (interface{}, Value, bool) {
 	switch vt {
 	case VTBoolean:
 		return false, NewExprValueMust(NewScalarTypeSignature(VTBoolean), false), true
+	case VTDecimal:
+		return Decimal{}, NewExprValueDecimal(Decimal{}), true
 	case VTInteger:
 		return 0, NewExprValueMust(NewScalarTypeSignature(VTInteger), 0), true
 	case VTString:
@@ -613,6 +915,18 @@ func (v ValueTypeMetadata) Comparable(vt ValueType) bool {
 	return v[vt].comparable
 }
 
+// ComparableType returns true if you may compare two values of the specified type signature. A list is comparable
+// if its unit type is comparable.
+func (v ValueTypeMetadata) ComparableType(ts TypeSignature) bool {
+	if !v.Comparable(ts.BaseType) {
+		return false
+	}
+	if ts.BaseType == VTList {
+		return ts.UnitType != nil && v.ComparableType(*ts.UnitType)
+	}
+	return true
+}
+
 // Searchable returns true is you ay search for sub values in a value of the specified value type
 func (v ValueTypeMetadata) Searchable(vt ValueType) bool {
 	return v[vt].searchable
@@ -644,17 +958,21 @@ func (v ValueTypeMetadata) Scalar(vt ValueType) bool {
 }
 
 var VTMetadata = ValueTypeMetadata{
-	VTBoolean: {true, false, false, false, false, false,
+	VTAny: {true, false, false, false, false, false,
+		true, true},
+	VTBoolean: {true, true, false, false, false, false,
+		true, true},
+	VTDecimal: {true, true, false, false, false, false,
 		true, true},
 	VTInteger: {true, true, false, false, false, false,
 		true, true},
-	VTList: {true, false, true, false, false, true,
+	VTList: {true, true, true, false, true, true,
 		false, false},
-	VTMap: {true, false, true, false, false, false,
+	VTMap: {true, false, true, false, true, true,
 		false, false},
 	VTRegexp: {true, false, false, false, false, false,
 		true, true},
-	VTString: {true, false, false, false, false, false,
+	VTString: {true, true, false, false, true, false,
 		true, true},
 }
 
@@ -2,6 +2,7 @@ package goexpr
 
 import (
 	"encoding/json"
+	"math"
 	"reflect"
 	"strings"
 	"testing"
@@ -51,6 +52,12 @@ func TestValue_Json(t *testing.T) {
 {"type":{"base_type":"regexp"},"value":"[0-9]{3}"}`},
 		{"string", NewExprValueString("a string"), `
 {"type":{"base_type":"string"},"value":"a string"}`},
+		{"any", NewExprValueAny(NewExprValueInteger(3)), `
+{"type":{"base_type":"any"},"value":{"type":{"base_type":"integer"},"value":3}}`},
+		{"nilAny", NewNilExprValue(NewScalarTypeSignature(VTAny)), `
+{"type":{"base_type":"any"}}`},
+		{"decimal", NewExprValueDecimal(ParseDecimalMust("12345678901234567890.10")), `
+{"type":{"base_type":"decimal"},"value":"12345678901234567890.10"}`},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -107,6 +114,13 @@ func TestValue_Equal(t *testing.T) {
 		{"integerFalseType",
 			NewExprValueInteger(2), NewExprValueString("2"), false},
 
+		{"anyTrue",
+			NewExprValueAny(NewExprValueInteger(2)), NewExprValueAny(NewExprValueInteger(2)), true},
+		{"anyFalse",
+			NewExprValueAny(NewExprValueInteger(2)), NewExprValueAny(NewExprValueString("2")), false},
+		{"anyFalseType",
+			NewExprValueAny(NewExprValueInteger(2)), NewExprValueInteger(2), false},
+
 		{"listTrue",
 			NewExprValueList(NewScalarTypeSignature(VTString), []Value{
 				NewExprValueString("value1"), NewExprValueString("value2")}),
@@ -200,6 +214,13 @@ func TestValue_Equal(t *testing.T) {
 }
 
 func TestValue_Compare(t *testing.T) {
+	intList := func(ints ...int) Value {
+		list := make([]Value, 0, len(ints))
+		for _, i := range ints {
+			list = append(list, NewExprValueInteger(i))
+		}
+		return NewExprValueList(NewScalarTypeSignature(VTInteger), list)
+	}
 	tests := []struct {
 		name   string
 		rv1    Value
@@ -229,6 +250,48 @@ func TestValue_Compare(t *testing.T) {
 			NewExprValueInteger(2), NewExprValueInteger(3), false, NewExprValueInteger(-1)},
 		{"integerGreater",
 			NewExprValueInteger(3), NewExprValueInteger(2), false, NewExprValueInteger(1)},
+		{"integerMinLess",
+			NewExprValueInteger(math.MinInt64), NewExprValueInteger(1), false, NewExprValueInteger(-1)},
+		{"integerMaxGreater",
+			NewExprValueInteger(math.MaxInt64), NewExprValueInteger(-1), false, NewExprValueInteger(1)},
+		{"listIntegerMin",
+			intList(1), intList(math.MinInt64), false, NewExprValueInteger(1)},
+		{"decimalEqualScale",
+			NewExprValueDecimal(ParseDecimalMust("1.5")), NewExprValueDecimal(ParseDecimalMust("1.50")), false,
+			NewExprValueInteger(0)},
+		{"decimalLess",
+			NewExprValueDecimal(ParseDecimalMust("-2.01")), NewExprValueDecimal(ParseDecimalMust("-2")), false,
+			NewExprValueInteger(-1)},
+		{"stringEqual",
+			NewExprValueString("foo"), NewExprValueString("foo"), false, NewExprValueInteger(0)},
+		{"stringLess",
+			NewExprValueString("bar"), NewExprValueString("foo"), false, NewExprValueInteger(-1)},
+		{"stringGreater",
+			NewExprValueString("foo"), NewExprValueString("Foo"), false, NewExprValueInteger(1)},
+		{"stringNil",
+			NewExprValueString("foo"), EvNilString, false, EvNilInteger},
+		{"booleanEqual",
+			EvBooleanTrue, EvBooleanTrue, false, NewExprValueInteger(0)},
+		{"booleanLess",
+			EvBooleanFalse, EvBooleanTrue, false, NewExprValueInteger(-1)},
+		{"booleanGreater",
+			EvBooleanTrue, EvBooleanFalse, false, NewExprValueInteger(1)},
+		{"listEqual",
+			intList(1, 2), intList(1, 2), false, NewExprValueInteger(0)},
+		{"listLess",
+			intList(1, 2), intList(1, 3), false, NewExprValueInteger(-1)},
+		{"listPrefix",
+			intList(1, 2), intList(1, 2, 3), false, NewExprValueInteger(-1)},
+		{"listGreater",
+			intList(2), intList(1, 5), false, NewExprValueInteger(1)},
+		{"listNilElement",
+			NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{EvNilInteger}), intList(1),
+			false, NewExprValueInteger(-1)},
+		{"regexp",
+			NewExprValueRegexpMust("a"), NewExprValueRegexpMust("b"), true, EvNilInteger},
+		{"map",
+			NewExprValueMap(NewScalarTypeSignature(VTInteger), map[string]Value{}),
+			NewExprValueMap(NewScalarTypeSignature(VTInteger), map[string]Value{}), true, EvNilInteger},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -248,6 +311,27 @@ func TestValue_Compare(t *testing.T) {
 	}
 }
 
+// foldCollator compares strings ignoring case
+type foldCollator struct{}
+
+func (foldCollator) CompareString(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func TestValue_CompareCollated(t *testing.T) {
+	res := NewExprValueString("foo").CompareCollated(NewExprValueString("Foo"), foldCollator{})
+	if !res.Equal(NewExprValueInteger(0)) {
+		t.Errorf("wrong compare result.\nactual:   %v\nexpected: %v", res, 0)
+	}
+	list := func(s string) Value {
+		return NewExprValueList(NewScalarTypeSignature(VTString), []Value{NewExprValueString(s)})
+	}
+	res = list("b").CompareCollated(list("A"), foldCollator{})
+	if !res.Equal(NewExprValueInteger(1)) {
+		t.Errorf("wrong compare result.\nactual:   %v\nexpected: %v", res, 1)
+	}
+}
+
 func TestValue_String(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -266,6 +350,9 @@ func TestValue_String(t *testing.T) {
 			`{key1:"value1",key2:"value2"}`},
 		{"regexp", NewExprValueRegexpMust("[0-9]{3}"), `"[0-9]{3}"`},
 		{"string", NewExprValueString("value"), `"value"`},
+		{"any", NewExprValueList(NewScalarTypeSignature(VTAny), []Value{
+			NewExprValueAny(NewExprValueString("a")), NewExprValueAny(NewExprValueInteger(1))}),
+			`["a",1]`},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -336,7 +423,74 @@ func TestValue_SearchAll(t *testing.T) {
 	}
 }
 
-// TODO test reference and assign for map
+func TestValue_Reference(t *testing.T) {
+	tsString := NewScalarTypeSignature(VTString)
+	strList := func(strs ...string) Value {
+		list := make([]Value, 0, len(strs))
+		for _, s := range strs {
+			list = append(list, NewExprValueString(s))
+		}
+		return NewExprValueList(tsString, list)
+	}
+	tests := []struct {
+		name  string
+		rv    Value
+		key   interface{}
+		rvRes Value
+	}{
+		{"map", NewExprValueMap(tsString, map[string]Value{
+			"foo": NewExprValueString("foo1"),
+		}), "foo", NewExprValueString("foo1")},
+		{"listIndex", strList("a", "b", "c"), 0, NewExprValueString("a")},
+		{"listIndexNegative", strList("a", "b", "c"), -1, NewExprValueString("c")},
+		{"listIndexOutOfRange", strList("a", "b", "c"), 3, EvNilString},
+		{"listIndexNegativeOutOfRange", strList("a", "b", "c"), -4, EvNilString},
+		{"listIndexEmpty", strList(), 0, EvNilString},
+		{"listSlice", strList("a", "b", "c"), Slice{Start: 1, End: 3}, strList("b", "c")},
+		{"listSliceNegative", strList("a", "b", "c"), Slice{Start: -2, End: -1}, strList("b")},
+		{"listSliceOpenEnd", strList("a", "b", "c"), Slice{Start: 1, OpenEnd: true}, strList("b", "c")},
+		{"listSliceOutOfRange", strList("a", "b", "c"), Slice{Start: -10, End: 10}, strList("a", "b", "c")},
+		{"listSliceEmpty", strList("a", "b", "c"), Slice{Start: 2, End: 1}, strList()},
+		{"stringIndex", NewExprValueString("åäö"), 1, NewExprValueString("ä")},
+		{"stringIndexNegative", NewExprValueString("foo"), -3, NewExprValueString("f")},
+		{"stringIndexOutOfRange", NewExprValueString("foo"), 3, EvNilString},
+		{"stringSlice", NewExprValueString("foobar"), Slice{Start: 0, End: 3}, NewExprValueString("foo")},
+		{"stringSliceOpenEnd", NewExprValueString("foobar"), Slice{Start: -3, OpenEnd: true},
+			NewExprValueString("bar")},
+		{"stringSliceOutOfRange", NewExprValueString("foobar"), Slice{Start: 4, End: 10}, NewExprValueString("ar")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := test.rv.Reference(test.key)
+			if !res.Equal(test.rvRes) || !res.Type.Equal(test.rvRes.Type) {
+				t.Errorf("wrong reference result.\nactual:   %v\nexpected: %v", res, test.rvRes)
+			}
+		})
+	}
+}
+
+func TestValue_ReferencePanic(t *testing.T) {
+	tests := []struct {
+		name string
+		rv   Value
+		key  interface{}
+	}{
+		{"integer", NewExprValueInteger(1), 0},
+		{"listStringKey", NewExprValueList(NewScalarTypeSignature(VTString), []Value{}), "foo"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("expected panic")
+				}
+			}()
+			test.rv.Reference(test.key)
+		})
+	}
+}
+
+// TODO test assign for map
 
 func TestValue_Nil(t *testing.T) {
 	tests := []struct {
@@ -380,6 +534,9 @@ func TestNewExprValue(t *testing.T) {
 		{"boolean/true", NewScalarTypeSignature(VTBoolean), true, NewExprValueBoolean(true)},
 		{"boolean/false", NewScalarTypeSignature(VTBoolean), false, NewExprValueBoolean(false)},
 		{"integer/int", NewScalarTypeSignature(VTInteger), 5, NewExprValueInteger(5)},
+		{"decimal/decimal", NewScalarTypeSignature(VTDecimal), ParseDecimalMust("0.25"),
+			NewExprValueDecimal(ParseDecimalMust("0.25"))},
+		{"decimal/int", NewScalarTypeSignature(VTDecimal), int64(5), NewExprValueDecimal(NewDecimalFromInt(5))},
 		{"integer/int8", NewScalarTypeSignature(VTInteger), int8(5), NewExprValueInteger(5)},
 		{"integer/int16", NewScalarTypeSignature(VTInteger), int16(5), NewExprValueInteger(5)},
 		{"integer/int32", NewScalarTypeSignature(VTInteger), int32(5), NewExprValueInteger(5)},
@@ -471,6 +628,24 @@ func (t testStringMethod) String() string {
 }
 
 func TestNewExprValueFromInterface(t *testing.T) {
+	fromJSON := func(js string) interface{} {
+		var v interface{}
+		err := json.Unmarshal([]byte(js), &v)
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}
+	fromJSONNumber := func(js string) interface{} {
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(js))
+		dec.UseNumber()
+		err := dec.Decode(&v)
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}
 	tests := []struct {
 		name  string
 		value interface{}
@@ -488,12 +663,37 @@ func TestNewExprValueFromInterface(t *testing.T) {
 		{"integer/uint16", uint16(5), NewExprValueInteger(5)},
 		{"integer/uint32", uint32(5), NewExprValueInteger(5)},
 		{"integer/uint64", uint64(5), NewExprValueInteger(5)},
+		{"integer/float64", float64(5), NewExprValueInteger(5)},
+		{"integer/float64Negative", float64(-5), NewExprValueInteger(-5)},
+		{"integer/jsonNumber", json.Number("5"), NewExprValueInteger(5)},
+		{"integer/jsonNumberExponent", json.Number("5e2"), NewExprValueInteger(500)},
+		{"integer/jsonNumberFraction", json.Number("5.0"), NewExprValueInteger(5)},
 		{"list", []interface{}{"v1"},
 			NewExprValueList(NewScalarTypeSignature(VTString),
 				[]Value{NewExprValueMust(NewScalarTypeSignature(VTString), "v1")})},
 		{"map", map[string]interface{}{"k1": "v1"},
 			NewExprValueMap(NewScalarTypeSignature(VTString),
 				map[string]Value{"k1": NewExprValueMust(NewScalarTypeSignature(VTString), "v1")})},
+		{"listNil", []interface{}{"v1", nil},
+			NewExprValueList(NewScalarTypeSignature(VTString), []Value{NewExprValueString("v1"), EvNilString})},
+		{"listMixed", fromJSON(`["a", 1, true, null]`),
+			NewExprValueList(NewScalarTypeSignature(VTAny), []Value{
+				NewExprValueAny(NewExprValueString("a")),
+				NewExprValueAny(NewExprValueInteger(1)),
+				NewExprValueAny(EvBooleanTrue),
+				NewNilExprValue(NewScalarTypeSignature(VTAny))})},
+		{"listMixedLists", []interface{}{[]interface{}{"a"}, []interface{}{1}},
+			NewExprValueList(NewScalarTypeSignature(VTAny), []Value{
+				NewExprValueAny(NewExprValueList(NewScalarTypeSignature(VTString), []Value{NewExprValueString("a")})),
+				NewExprValueAny(NewExprValueList(NewScalarTypeSignature(VTInteger), []Value{NewExprValueInteger(1)}))})},
+		{"listMixedNumber", fromJSONNumber(`["a", 1]`),
+			NewExprValueList(NewScalarTypeSignature(VTAny), []Value{
+				NewExprValueAny(NewExprValueString("a")),
+				NewExprValueAny(NewExprValueInteger(1))})},
+		{"mapMixed", fromJSON(`{"name": "foo", "age": 42}`),
+			NewExprValueMap(NewScalarTypeSignature(VTAny), map[string]Value{
+				"name": NewExprValueAny(NewExprValueString("foo")),
+				"age":  NewExprValueAny(NewExprValueInteger(42))})},
 		{"nil", nil, EvNil},
 		{"string", "a string", NewExprValueString("a string")},
 		//------------------------
@@ -516,6 +716,30 @@ func TestNewExprValueFromInterface(t *testing.T) {
 	}
 }
 
+func TestNewExprValueFromInterfaceError(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"fraction", 1.5},
+		{"nan", math.NaN()},
+		{"infinity", math.Inf(1)},
+		{"outOfRange", 1e19},
+		{"jsonNumberFraction", json.Number("1.5")},
+		{"jsonNumberOutOfRange", json.Number("12345678901234567890")},
+		{"listFraction", []interface{}{1.0, 1.5}},
+		{"unsupported", struct{}{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewExprValueFromInterface(test.value)
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
 func TestNewExprValueFromString(t *testing.T) {
 	tests := []struct {
 		name string
@@ -526,6 +750,7 @@ func TestNewExprValueFromString(t *testing.T) {
 		{"boolean/true", NewScalarTypeSignature(VTBoolean), "true", NewExprValueBoolean(true)},
 		{"boolean/false", NewScalarTypeSignature(VTBoolean), "false", NewExprValueBoolean(false)},
 		{"integer", NewScalarTypeSignature(VTInteger), "5", NewExprValueInteger(5)},
+		{"decimal", NewScalarTypeSignature(VTDecimal), "-0.05", NewExprValueDecimal(NewDecimal(-5, 2))},
 		// List not supported
 		// Map not supported
 		{"nil", TsNil, "nil", EvNil},
@@ -555,6 +780,7 @@ func TestNewExprValueFromStringError(t *testing.T) {
 	}{
 		{"boolean", NewScalarTypeSignature(VTBoolean), "not a boolean", NewNilExprValue(NewScalarTypeSignature(VTBoolean))},
 		{"integer", NewScalarTypeSignature(VTInteger), "not an integer", NewNilExprValue(NewScalarTypeSignature(VTInteger))},
+		{"decimal", NewScalarTypeSignature(VTDecimal), "1.2.3", NewNilExprValue(NewScalarTypeSignature(VTDecimal))},
 		{"list", NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString)), "list unsupported",
 			NewNilExprValue(NewCompositeTypeSignature(VTList, NewScalarTypeSignature(VTString)))},
 		{"map", NewCompositeTypeSignature(VTMap, NewScalarTypeSignature(VTString)), "map unsupported",
@@ -0,0 +1,158 @@
+package goexpr
+
+import (
+	"fmt"
+)
+
+// Visitor is used by Walk to visit the expressions of an expression tree. Visit is called for each expression
+// encountered by Walk. If the returned visitor w is not nil then Walk visits each of the sub-expressions of the
+// expression with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(expr Expression) (w Visitor)
+}
+
+// Walk traverses an expression tree in depth-first order. It starts by calling v.Visit(expr). The sub-expressions
+// are visited in evaluation order (see Expression.Children()).
+func Walk(v Visitor, expr Expression) {
+	if v = v.Visit(expr); v == nil {
+		return
+	}
+	for _, child := range expr.Children() {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Expression) bool
+
+func (f inspector) Visit(expr Expression) Visitor {
+	if f(expr) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an expression tree in depth-first order. It starts by calling f(expr). If f returns true
+// Inspect is called recursively for each of the sub-expressions of expr, followed by a call of f(nil).
+func Inspect(expr Expression, f func(Expression) bool) {
+	Walk(inspector(f), expr)
+}
+
+// Rewrite rewrites an expression tree bottom-up. The sub-expressions of an expression are rewritten before the
+// expression itself. The function f is then called with the expression (with rewritten sub-expressions) and the
+// expression returned by f replaces the expression in the tree. An expression is only copied if any of its
+// sub-expressions has been replaced. If f returns an error the rewrite is aborted and the error is returned.
+func Rewrite(expr Expression, f func(Expression) (Expression, error)) (Expression, error) {
+	children := expr.Children()
+	if len(children) > 0 {
+		newChildren := make([]Expression, len(children))
+		changed := false
+		for i, child := range children {
+			newChild, err := Rewrite(child, f)
+			if err != nil {
+				return nil, err
+			}
+			newChildren[i] = newChild
+			changed = changed || newChild != child
+		}
+		if changed {
+			newExpr, err := expr.WithChildren(newChildren)
+			if err != nil {
+				return nil, fmt.Errorf("error rewriting expression %v (%d:%d): %v", expr, expr.Line(), expr.Col(), err)
+			}
+			expr = newExpr
+		}
+	}
+	return f(expr)
+}
+
+// ReferencedKeys returns the keys of all request context heap references read by the expression tree.
+// References to names bound by a let expression are not included.
+// Each key is only returned once and the keys are returned in evaluation order.
+func ReferencedKeys(expr Expression) []interface{} {
+	var keys keySet
+	inspectScoped(expr, func(e Expression, scope letScope) {
+		ref, ok := e.(*exprReference)
+		if ok && ref.source == RSHeap && !scope.bound(ref.key) {
+			keys.add(ref.key)
+		}
+	})
+	return keys.keys
+}
+
+// AssignedKeys returns the keys of all request context heap references written by the expression tree. This includes
+// both assignments and the keys assigned by for loops, lambdas, sorts and switches. Keys equal to names bound by an
+// enclosing let expression are not included.
+// Each key is only returned once and the keys are returned in evaluation order.
+func AssignedKeys(expr Expression) []interface{} {
+	var keys keySet
+	inspectScoped(expr, func(e Expression, scope letScope) {
+		switch op := e.(type) {
+		case *exprAssign:
+			if op.source == RSHeap && !scope.bound(op.key) {
+				keys.add(op.key)
+			}
+		case *exprFor:
+			if !scope.bound(op.key) {
+				keys.add(op.key)
+			}
+		case *exprSwitch:
+			if op.key != "" && !scope.bound(op.key) {
+				keys.add(op.key)
+			}
+		case *exprLambda:
+			if !scope.bound(op.key) {
+				keys.add(op.key)
+			}
+			if op.lt == LATReduce && !scope.bound(op.accKey) {
+				keys.add(op.accKey)
+			}
+		case *exprSort:
+			if op.opKey != nil && !scope.bound(op.key) {
+				keys.add(op.key)
+			}
+		}
+	})
+	return keys.keys
+}
+
+// MatchRegexps returns all constant regexps used by match compare expressions in the expression tree. The returned
+// values are of type VTRegexp and include the pre-compiled regexp.
+func MatchRegexps(expr Expression) []Value {
+	var regexps []Value
+	Inspect(expr, func(e Expression) bool {
+		op, ok := e.(*exprCompare)
+		if !ok || op.ct != CTMatch {
+			return true
+		}
+		constant, ok := op.opRight.(*exprConstant)
+		if ok && constant.ResultType().IsValueType(VTRegexp) && !constant.c.Nil() {
+			regexps = append(regexps, constant.c)
+		}
+		return true
+	})
+	return regexps
+}
+
+// keySet holds an ordered set of reference keys. As keys may be of any type (including non-comparable types) the
+// string representation of the key is used to identify the key.
+type keySet struct {
+	keys []interface{}
+	seen map[string]bool
+}
+
+func (ks *keySet) add(key interface{}) {
+	if ks.seen == nil {
+		ks.seen = make(map[string]bool)
+	}
+	keyS := fmt.Sprint(key)
+	if ks.seen[keyS] {
+		return
+	}
+	ks.seen[keyS] = true
+	ks.keys = append(ks.keys, key)
+}
+
+func (ks *keySet) contains(key interface{}) bool {
+	return ks.seen[fmt.Sprint(key)]
+}
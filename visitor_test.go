@@ -0,0 +1,193 @@
+package goexpr
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExpression_Children(t *testing.T) {
+	l, c := 1, 2
+	tests := []struct {
+		name     string
+		op       Expression
+		children []string
+	}{
+		{"assign", NewExprAssign("assign", "my/ref",
+			NewExprConstant(NewExprValueBoolean(true), l, c), nil, RSHeap, l, c),
+			[]string{`true`}},
+		{"compare", NewExprCompareMust(CTLess, NewExprConstant(NewExprValueInteger(1), l, c),
+			NewExprConstant(NewExprValueInteger(2), l, c), l, c),
+			[]string{`1`, `2`}},
+		{"constant", NewExprConstant(NewExprValueString("foo"), l, c),
+			nil},
+		{"forNoBreak", NewExprFor(
+			NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+				NewExprValueString("foo"),
+			}), l, c),
+			NewExprHeapReference("loop", "k1", l, c),
+			nil,
+			"k1", l, c),
+			[]string{`["foo"]`, `k1`}},
+		{"forBreak", NewExprFor(
+			NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+				NewExprValueString("foo"),
+			}), l, c),
+			NewExprHeapReference("loop", "k1", l, c),
+			NewExprConstant(NewExprValueString("foo"), l, c),
+			"k1", l, c),
+			[]string{`["foo"]`, `"foo"`, `k1`}},
+		{"ifThen", NewExprIf(NewExprConstant(NewExprValueBoolean(true), l, c),
+			NewExprConstant(NewExprValueString("then"), l, c), nil, l, c),
+			[]string{`true`, `"then"`}},
+		{"ifElse", NewExprIf(NewExprConstant(NewExprValueBoolean(false), l, c),
+			NewExprConstant(NewExprValueString("then"), l, c),
+			NewExprConstant(NewExprValueString("else"), l, c), l, c),
+			[]string{`false`, `"then"`, `"else"`}},
+		{"logicalAnd", NewExprLogical(LTAnd, NewExprConstant(NewExprValueBoolean(true), l, c),
+			NewExprConstant(NewExprValueBoolean(false), l, c), l, c),
+			[]string{`true`, `false`}},
+		{"logicalNot", NewExprLogicalUnary(LTNot, NewExprConstant(NewExprValueBoolean(true), l, c), l, c),
+			[]string{`true`}},
+		{"heapReference", NewExprHeapReference("ref", "my/ref", l, c),
+			nil},
+		{"valueReference", NewExprValueReference("ref", "key", NewExprHeapReference("ref", "my/ref", l, c), l, c),
+			[]string{`my/ref`}},
+		{"searchDefault", NewExprSearch(
+			NewExprConstant(NewExprValueString("foo"), l, c),
+			NewExprHeapReference("ref", "my/ref", l, c),
+			NewExprConstant(NewExprValueString("baz"), l, c), STFind, NewScalarTypeSignature(VTString), l, c),
+			[]string{`"foo"`, `my/ref`, `"baz"`}},
+		{"sequence", NewExprSequence([]Expression{
+			NewExprConstant(NewExprValueString("foo"), l, c),
+			NewExprConstant(NewExprValueBoolean(true), l, c)}, l, c),
+			[]string{`"foo"`, `true`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			children := test.op.Children()
+			if len(children) != len(test.children) {
+				t.Errorf("wrong number of children (%d != %d)", len(children), len(test.children))
+				return
+			}
+			for i, child := range children {
+				if child.String() != test.children[i] {
+					t.Errorf("wrong child %d.\nactual:   %v\nexpected: %v", i, child.String(), test.children[i])
+				}
+			}
+			// Modifying the returned children should not modify the expression
+			if len(children) > 0 {
+				str := test.op.String()
+				first := children[0]
+				children[0] = NewExprConstant(NewExprValueString("modified"), l, c)
+				if test.op.String() != str {
+					t.Errorf("expression modified through its children.\nactual:   %v\nexpected: %v", test.op, str)
+				}
+				children[0] = first
+			}
+			// Replacing the children with the same children should give an equal expression
+			cp, err := test.op.WithChildren(children)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if cp == test.op {
+				t.Errorf("expected a copy of the expression")
+			}
+			if cp.String() != test.op.String() {
+				t.Errorf("wrong copy.\nactual:   %v\nexpected: %v", cp, test.op)
+			}
+			// Wrong number of children should give an error
+			_, err = test.op.WithChildren(append(children, NewExprConstant(EvNil, l, c)))
+			if err == nil {
+				t.Errorf("expected error for wrong number of children")
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	l, c := 1, 2
+	op := NewExprIf(
+		NewExprCompareMust(CTEqual, NewExprHeapReference("state", "state", l, c),
+			NewExprConstant(NewExprValueString("ok"), l, c), l, c),
+		NewExprConstant(NewExprValueInteger(1), l, c),
+		NewExprConstant(NewExprValueInteger(2), l, c), l, c)
+	var visited []string
+	Inspect(op, func(e Expression) bool {
+		if e != nil {
+			visited = append(visited, e.String())
+		}
+		return true
+	})
+	expected := []string{`(if (state == "ok") then 1 else 2)`, `(state == "ok")`, `state`, `"ok"`, `1`, `2`}
+	if fmt.Sprint(visited) != fmt.Sprint(expected) {
+		t.Errorf("wrong visited expressions.\nactual:   %v\nexpected: %v", visited, expected)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	l, c := 1, 2
+	op := NewExprSequence([]Expression{
+		NewExprAssign("ref", "a", NewExprConstant(NewExprValueString("foo"), l, c), nil, RSHeap, l, c),
+		NewExprCompareMust(CTEqual, NewExprHeapReference("ref", "a", l, c),
+			NewExprConstant(NewExprValueString("foo"), l, c), l, c),
+	}, l, c)
+	// Replace all "foo" constants with "bar"
+	rewritten, err := Rewrite(op, func(e Expression) (Expression, error) {
+		constant, ok := e.(*exprConstant)
+		if ok && constant.c.Equal(NewExprValueString("foo")) {
+			return NewExprConstant(NewExprValueString("bar"), e.Line(), e.Col()), nil
+		}
+		return e, nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	expected := `{(a = "bar") (a == "bar")}`
+	if rewritten.String() != expected {
+		t.Errorf("wrong rewritten expression.\nactual:   %v\nexpected: %v", rewritten, expected)
+	}
+	// The original expression must not be changed
+	expected = `{(a = "foo") (a == "foo")}`
+	if op.String() != expected {
+		t.Errorf("original expression changed.\nactual:   %v\nexpected: %v", op, expected)
+	}
+	res, err := rewritten.Evaluate(newEmptyTestRequestContext())
+	if err != nil {
+		t.Errorf("unexpected evaluation error: %v", err)
+		return
+	}
+	if !res.Equal(EvBooleanTrue) {
+		t.Errorf("wrong evaluation result.\nactual:   %v\nexpected: %v", res, EvBooleanTrue)
+	}
+}
+
+func TestReferencedAndAssignedKeys(t *testing.T) {
+	l, c := 1, 2
+	op := NewExprSequence([]Expression{
+		NewExprAssign("ref", "a", NewExprHeapReference("ref", "b", l, c), nil, RSHeap, l, c),
+		NewExprFor(
+			NewExprConstant(NewExprValueList(NewScalarTypeSignature(VTString), []Value{
+				NewExprValueString("foo"),
+			}), l, c),
+			NewExprCompareMust(CTMatch, NewExprHeapReference("loop", "k1", l, c),
+				NewExprConstant(NewExprValueString("[a-z]+"), l, c), l, c),
+			nil,
+			"k1", l, c),
+		NewExprHeapReference("ref", "a", l, c),
+		NewExprHeapReference("ref", "b", l, c),
+	}, l, c)
+	referenced := fmt.Sprint(ReferencedKeys(op))
+	if referenced != "[b k1 a]" {
+		t.Errorf("wrong referenced keys.\nactual:   %v\nexpected: %v", referenced, "[b k1 a]")
+	}
+	assigned := fmt.Sprint(AssignedKeys(op))
+	if assigned != "[a k1]" {
+		t.Errorf("wrong assigned keys.\nactual:   %v\nexpected: %v", assigned, "[a k1]")
+	}
+	regexps := MatchRegexps(op)
+	if len(regexps) != 1 || !regexps[0].Equal(NewExprValueRegexpMust("[a-z]+")) || regexps[0].Regexp == nil {
+		t.Errorf("wrong match regexps: %v", regexps)
+	}
+}